@@ -1,10 +1,145 @@
 package agent
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aviate-labs/leb128"
 	"github.com/icpfans-xyz/agent-go/principal"
 )
 
+const (
+	// DefaultPollInterval is the default delay between two polls of the status
+	// of an update call.
+	DefaultPollInterval = time.Second
+	// DefaultCallTimeout is the default time to wait for the reply to an
+	// update call.
+	DefaultCallTimeout = 5 * time.Minute
+)
+
+// RejectError is a call that was rejected by the IC or by the canister.
+type RejectError struct {
+	Code    ReplicaRejectCode
+	Message string
+}
+
+func (e *RejectError) Error() string {
+	return fmt.Sprintf("call rejected (code %d): %s", e.Code, e.Message)
+}
+
 type CallConfig struct {
 	Agent      Agent
 	CanisterId principal.Principal
+	// The delay between two polls of the status of an update call,
+	// DefaultPollInterval if zero.
+	PollInterval time.Duration
+	// The time to wait for the reply to an update call, DefaultCallTimeout if
+	// zero.
+	Timeout time.Duration
+}
+
+// Query calls a query method of the canister and returns the Candid-encoded
+// reply.
+func (c CallConfig) Query(methodName string, arg []byte) ([]byte, error) {
+	resp, err := c.Agent.Query(&c.CanisterId, &QueryFields{
+		MethodName: methodName,
+		Arg:        arg,
+	})
+	if err != nil {
+		return nil, err
+	}
+	switch resp.Status {
+	case QueryResponseStatusReplied:
+		return resp.Reply["arg"], nil
+	case QueryResponseStatusRejected:
+		return nil, &RejectError{
+			Code:    ReplicaRejectCode(resp.RejectCode),
+			Message: resp.RejectMsg,
+		}
+	}
+	return nil, fmt.Errorf("unexpected query status %q", resp.Status)
+}
+
+// Update calls an update method of the canister and polls its status until
+// the call is replied, which returns the Candid-encoded reply.
+func (c CallConfig) Update(methodName string, arg []byte) ([]byte, error) {
+	resp, err := c.Agent.Call(&c.CanisterId, &CallOptions{
+		MethodName: methodName,
+		Arg:        arg,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.pollForReply(resp.RequestId)
+}
+
+// Oneway calls a method of the canister without waiting for it to run.
+func (c CallConfig) Oneway(methodName string, arg []byte) error {
+	_, err := c.Agent.Call(&c.CanisterId, &CallOptions{
+		MethodName: methodName,
+		Arg:        arg,
+	})
+	return err
+}
+
+func (c CallConfig) pollForReply(requestId RequestId) ([]byte, error) {
+	interval := c.PollInterval
+	if interval == 0 {
+		interval = DefaultPollInterval
+	}
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = DefaultCallTimeout
+	}
+	deadline := time.Now().Add(timeout)
+	path := [][]byte{[]byte("request_status"), requestId[:]}
+	for {
+		state, err := c.Agent.ReadState(&c.CanisterId, &ReadStateOptions{
+			Paths: [][][]byte{path},
+		})
+		if err != nil {
+			return nil, err
+		}
+		cert, err := ParseCertificate(state.Certificate, c.Agent.RootKey(), &c.CanisterId)
+		if err != nil {
+			return nil, err
+		}
+		if !cert.Verify() {
+			return nil, errors.New("failed to verify certificate")
+		}
+		lookup := func(label string) ([]byte, error) {
+			return cert.Lookup(append(path, []byte(label)))
+		}
+		// The status is unknown until the call is received.
+		status, _ := lookup("status")
+		switch string(status) {
+		case "replied":
+			return lookup("reply")
+		case "rejected":
+			code, err := lookup("reject_code")
+			if err != nil {
+				return nil, err
+			}
+			n, err := leb128.DecodeUnsigned(bytes.NewReader(code))
+			if err != nil {
+				return nil, err
+			}
+			message, err := lookup("reject_message")
+			if err != nil {
+				return nil, err
+			}
+			return nil, &RejectError{
+				Code:    ReplicaRejectCode(n.Uint64()),
+				Message: string(message),
+			}
+		case "done":
+			return nil, errors.New("call was marked as done but the reply is no longer available")
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("request %x timed out after %s with status %q", requestId, timeout, status)
+		}
+		time.Sleep(interval)
+	}
 }
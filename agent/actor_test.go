@@ -0,0 +1,169 @@
+package agent_test
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/icpfans-xyz/agent-go/agent"
+	"github.com/icpfans-xyz/agent-go/principal"
+	"github.com/stretchr/testify/assert"
+)
+
+// queryAgent is an agent that answers queries with a fixed response.
+type queryAgent struct {
+	agent.Agent
+	response *agent.QueryResponse
+	method   string
+}
+
+func (a *queryAgent) Query(canisterId *principal.Principal, options *agent.QueryFields) (*agent.QueryResponse, error) {
+	a.method = options.MethodName
+	return a.response, nil
+}
+
+func (a *queryAgent) Call(canisterId *principal.Principal, options *agent.CallOptions) (*agent.SubmitResponse, error) {
+	a.method = options.MethodName
+	return nil, errors.New("unavailable")
+}
+
+func TestCallConfig(t *testing.T) {
+	t.Run("replied", func(t *testing.T) {
+		a := &queryAgent{response: &agent.QueryResponse{
+			Status: agent.QueryResponseStatusReplied,
+			Reply:  map[string][]byte{"arg": []byte("DIDL\x00\x00")},
+		}}
+		reply, err := agent.CallConfig{Agent: a}.Query("greet", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "greet", a.method)
+		assert.Equal(t, []byte("DIDL\x00\x00"), reply)
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		a := &queryAgent{response: &agent.QueryResponse{
+			Status:     agent.QueryResponseStatusRejected,
+			RejectCode: 4,
+			RejectMsg:  "no such method",
+		}}
+		_, err := agent.CallConfig{Agent: a}.Query("greet", nil)
+		var reject *agent.RejectError
+		if assert.True(t, errors.As(err, &reject)) {
+			assert.Equal(t, agent.ReplicaRejectCode(4), reject.Code)
+			assert.Equal(t, "call rejected (code 4): no such method", err.Error())
+		}
+	})
+
+	t.Run("call error", func(t *testing.T) {
+		a := &queryAgent{}
+		_, err := agent.CallConfig{Agent: a}.Update("transfer", nil)
+		assert.EqualError(t, err, "unavailable")
+		assert.Equal(t, "transfer", a.method)
+		assert.EqualError(t, agent.CallConfig{Agent: a}.Oneway("notify", nil), "unavailable")
+	})
+}
+
+// pollAgent is an agent that accepts calls and answers the reads of their
+// status with certificates of a tree per status, the last one repeated.
+type pollAgent struct {
+	agent.Agent
+	t         *testing.T
+	root      *blsKey
+	requestId agent.RequestId
+	states    []map[string][]byte
+	reads     int
+}
+
+func (a *pollAgent) RootKey() []byte {
+	return a.root.der()
+}
+
+func (a *pollAgent) Call(canisterId *principal.Principal, options *agent.CallOptions) (*agent.SubmitResponse, error) {
+	return &agent.SubmitResponse{RequestId: a.requestId}, nil
+}
+
+func (a *pollAgent) ReadState(canisterId *principal.Principal, options *agent.ReadStateOptions) (*agent.ReadStateResponse, error) {
+	state := a.states[len(a.states)-1]
+	if a.reads < len(a.states) {
+		state = a.states[a.reads]
+	}
+	a.reads++
+	var labels []string
+	for label := range state {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	var status agent.HashTree
+	for _, label := range labels {
+		sub := labeled([]byte(label), agent.HashTree{agent.Leaf, state[label]})
+		if status == nil {
+			status = sub
+		} else {
+			status = agent.HashTree{agent.Fork, status, sub}
+		}
+	}
+	tree := labeled([]byte("request_status"), labeled(a.requestId[:], status))
+	return &agent.ReadStateResponse{Certificate: a.root.certify(a.t, tree, nil)}, nil
+}
+
+func TestCallConfigUpdate(t *testing.T) {
+	processing := map[string][]byte{"status": []byte("processing")}
+	config := func(a *pollAgent) agent.CallConfig {
+		return agent.CallConfig{Agent: a, PollInterval: time.Millisecond, Timeout: time.Second}
+	}
+
+	t.Run("replied", func(t *testing.T) {
+		a := &pollAgent{t: t, root: newBlsKey(1), requestId: agent.RequestId{1}, states: []map[string][]byte{
+			processing,
+			processing,
+			{"status": []byte("replied"), "reply": []byte("DIDL\x00\x00")},
+		}}
+		reply, err := config(a).Update("transfer", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("DIDL\x00\x00"), reply)
+		assert.Equal(t, 3, a.reads)
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		a := &pollAgent{t: t, root: newBlsKey(1), requestId: agent.RequestId{2}, states: []map[string][]byte{
+			processing,
+			{"status": []byte("rejected"), "reject_code": {5}, "reject_message": []byte("canister trapped")},
+		}}
+		_, err := config(a).Update("transfer", nil)
+		var reject *agent.RejectError
+		if assert.True(t, errors.As(err, &reject)) {
+			assert.Equal(t, agent.ReplicaRejectCode(5), reject.Code)
+			assert.Equal(t, "canister trapped", reject.Message)
+		}
+		assert.Equal(t, 2, a.reads)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		a := &pollAgent{t: t, root: newBlsKey(1), requestId: agent.RequestId{3}, states: []map[string][]byte{processing}}
+		c := config(a)
+		c.Timeout = 20 * time.Millisecond
+		_, err := c.Update("transfer", nil)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "timed out after 20ms with status \"processing\"")
+		}
+		assert.True(t, a.reads > 1)
+	})
+
+	t.Run("other root key", func(t *testing.T) {
+		a := &pollAgent{t: t, root: newBlsKey(1), requestId: agent.RequestId{4}, states: []map[string][]byte{processing}}
+		c := config(a)
+		c.Agent = &otherRootAgent{a}
+		_, err := c.Update("transfer", nil)
+		assert.EqualError(t, err, "failed to verify certificate")
+	})
+}
+
+// otherRootAgent is a pollAgent whose root key did not sign the
+// certificates.
+type otherRootAgent struct {
+	*pollAgent
+}
+
+func (a *otherRootAgent) RootKey() []byte {
+	return newBlsKey(2).der()
+}
@@ -1,6 +1,12 @@
 package agent
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/icpfans-xyz/agent-go/identity"
 	"github.com/icpfans-xyz/agent-go/principal"
 )
@@ -8,9 +14,40 @@ import (
 var DomainSeparator = []byte("\x0Aic-request")
 
 type SignTransformBody struct {
-	Content      Request `cbor:"content,omitempty"`
-	SenderPubkey []byte  `cbor:"sender_pubkey,omitempty"`
-	SenderSig    []byte  `cbor:"sender_sig,omitempty"`
+	Content          Request            `cbor:"content,omitempty"`
+	SenderPubkey     []byte             `cbor:"sender_pubkey,omitempty"`
+	SenderSig        []byte             `cbor:"sender_sig,omitempty"`
+	SenderDelegation []SignedDelegation `cbor:"sender_delegation,omitempty"`
+}
+
+// Verify checks a signed envelope: the sender must be the self-authenticating
+// principal of the sender public key, the delegations (if any) must be valid
+// at the given time and allow the target canister, and the sender signature
+// must be made by the session key over the request ID. The DER-encoded root
+// key is used for delegations signed with canister signatures.
+func (b *SignTransformBody) Verify(now time.Time, rootKey []byte) error {
+	sender, err := principal.SelfAuthenticating(b.SenderPubkey)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(sender.ToBytes(), b.Content.Sender) {
+		return errors.New("sender does not match the sender public key")
+	}
+	chain := DelegationChain{
+		Delegations: b.SenderDelegation,
+		PublicKey:   b.SenderPubkey,
+	}
+	if err := chain.Verify(now, rootKey); err != nil {
+		return err
+	}
+	if b.Content.CanisterID != nil && !chain.IsValidTarget(principal.NewPrincipal(b.Content.CanisterID)) {
+		return errors.New("canister is not a target of the delegations")
+	}
+	requestId := RequestIdOf(b.Content)
+	if err := verifySignature(chain.SessionKey(), append(DomainSeparator, requestId[:]...), b.SenderSig, rootKey); err != nil {
+		return fmt.Errorf("sender signature: %v", err)
+	}
+	return nil
 }
 
 type AnonymousTransformBody struct {
@@ -25,23 +62,33 @@ type TransformRequest struct {
 type Signature = []byte
 
 type SignIdentity struct {
-	key       identity.KeyPair
+	signer    identity.Signer
 	principal *principal.Principal
 }
 
 func NewSignIdentity(key identity.KeyPair, principal *principal.Principal) *SignIdentity {
+	return NewSignerIdentity(identity.NewKeyPairSigner(key), principal)
+}
+
+// NewSignerIdentity creates an identity that signs with an external signer,
+// such as an HSM or a remote signing service.
+func NewSignerIdentity(signer identity.Signer, principal *principal.Principal) *SignIdentity {
 	return &SignIdentity{
-		key:       key,
+		signer:    signer,
 		principal: principal,
 	}
 }
 
 func (s *SignIdentity) GetPublicKey() identity.PublicKey {
-	return s.key.PublicKey()
+	return s.signer.PublicKey()
 }
 
 func (s *SignIdentity) Sign(blob []byte) (Signature, error) {
-	return s.key.Sign(blob)
+	return s.SignContext(context.Background(), blob)
+}
+
+func (s *SignIdentity) SignContext(ctx context.Context, blob []byte) (Signature, error) {
+	return s.signer.Sign(ctx, blob)
 }
 
 func (s *SignIdentity) GetPrincipal() *principal.Principal {
@@ -0,0 +1,32 @@
+package agent
+
+import (
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+// Domain separation tag of the BLS signature scheme used by the IC, which
+// places signatures in G1 and public keys in G2.
+var blsDST = []byte("BLS_SIG_BLS12381G1_XMD:SHA-256_SSWU_RO_NUL_")
+
+// blsVerify checks a BLS signature by verifying that
+// e(sig, g2) == e(H(msg), pk).
+func blsVerify(pk []byte, sig []byte, msg []byte) bool {
+	g1 := bls12381.NewG1()
+	g2 := bls12381.NewG2()
+	s, err := g1.FromCompressed(sig)
+	if err != nil || !g1.InCorrectSubgroup(s) {
+		return false
+	}
+	p, err := g2.FromCompressed(pk)
+	if err != nil || !g2.InCorrectSubgroup(p) || g2.IsZero(p) {
+		return false
+	}
+	h, err := g1.HashToCurve(msg, blsDST)
+	if err != nil {
+		return false
+	}
+	engine := bls12381.NewEngine()
+	engine.AddPair(h, p)
+	engine.AddPairInv(s, g2.One())
+	return engine.Check()
+}
@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/icpfans-xyz/agent-go/identity"
+)
+
+// Tag 55799 (self-described CBOR) as it prefixes an encoded value.
+var selfDescribedCBOR = []byte{0xd9, 0xd9, 0xf7}
+
+// DOCS: https://smartcontracts.org/docs/interface-spec/index.html#canister-signatures
+type CanisterSignature struct {
+	// A certificate of the subnet the canister runs on.
+	Certificate []byte `cbor:"certificate"`
+	// A hash tree whose root hash is the certified data of the canister.
+	Tree HashTree `cbor:"tree"`
+}
+
+// VerifyCanisterSignature checks the canister signature of the message for
+// the DER-encoded canister signature public key. The certificate contained in
+// the signature is verified against the DER-encoded root key.
+func VerifyCanisterSignature(publicKey []byte, message []byte, signature []byte, rootKey []byte) error {
+	pub, err := identity.ParseCanisterSigPublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+	var sig CanisterSignature
+	if err := cbor.Unmarshal(bytes.TrimPrefix(signature, selfDescribedCBOR), &sig); err != nil {
+		return fmt.Errorf("invalid canister signature: %v", err)
+	}
+	if len(sig.Tree) == 0 {
+		return errors.New("invalid canister signature: missing tree")
+	}
+	cert, err := ParseCertificate(sig.Certificate, rootKey, pub.CanisterID)
+	if err != nil {
+		return err
+	}
+	if !cert.Verify() {
+		return errors.New("fail to verify certificate")
+	}
+	certifiedData, err := cert.Lookup([][]byte{
+		[]byte("canister"), pub.CanisterID.ToBytes(), []byte("certified_data"),
+	})
+	if err != nil {
+		return errors.New("certificate does not contain certified data of the canister")
+	}
+	root, err := Reconstruct(sig.Tree)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(root, certifiedData) {
+		return errors.New("tree does not match certified data")
+	}
+	seedHash := sha256.Sum256(pub.Seed)
+	messageHash := sha256.Sum256(message)
+	leaf, err := LookupPath([][]byte{[]byte("sig"), seedHash[:], messageHash[:]}, sig.Tree)
+	if err != nil {
+		return errors.New("tree does not contain the signature")
+	}
+	if len(leaf) != 0 {
+		return errors.New("signature leaf must be empty")
+	}
+	return nil
+}
+
+// verifySignature checks a signature of any supported key type, including
+// canister signatures, which are verified against the root key.
+func verifySignature(publicKey []byte, message []byte, signature []byte, rootKey []byte) error {
+	if identity.IsCanisterSigPublicKey(publicKey) {
+		return VerifyCanisterSignature(publicKey, message, signature, rootKey)
+	}
+	return identity.Verify(publicKey, message, signature)
+}
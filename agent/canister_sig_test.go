@@ -0,0 +1,152 @@
+package agent_test
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/icpfans-xyz/agent-go/agent"
+	"github.com/icpfans-xyz/agent-go/identity"
+	"github.com/icpfans-xyz/agent-go/principal"
+	bls12381 "github.com/kilic/bls12-381"
+	"github.com/stretchr/testify/assert"
+)
+
+type blsKey struct {
+	sk *bls12381.Fr
+}
+
+func newBlsKey(seed byte) *blsKey {
+	return &blsKey{sk: bls12381.NewFr().FromBytes([]byte{seed, 0x42, 0x13, 0x37})}
+}
+
+func (k *blsKey) der() []byte {
+	g2 := bls12381.NewG2()
+	pk := g2.MulScalar(g2.New(), g2.One(), k.sk)
+	return append(append([]byte{}, agent.DER_PREFIX...), g2.ToCompressed(pk)...)
+}
+
+// certify signs the root hash of the tree and returns the CBOR certificate.
+func (k *blsKey) certify(t *testing.T, tree agent.HashTree, delegation *agent.Delegation) []byte {
+	root, err := agent.Reconstruct(tree)
+	assert.Nil(t, err)
+	g1 := bls12381.NewG1()
+	h, err := g1.HashToCurve(append([]byte("\x0dic-state-root"), root...), []byte("BLS_SIG_BLS12381G1_XMD:SHA-256_SSWU_RO_NUL_"))
+	assert.Nil(t, err)
+	sig := g1.ToCompressed(g1.MulScalar(g1.New(), h, k.sk))
+	data, err := cbor.Marshal(agent.Cert{Tree: tree, Signature: sig, Delegation: delegation})
+	assert.Nil(t, err)
+	return data
+}
+
+func labeled(label []byte, sub agent.HashTree) agent.HashTree {
+	return agent.HashTree{agent.Labeled, label, sub}
+}
+
+func TestVerifyCanisterSignature(t *testing.T) {
+	canisterId, _ := principal.FromString("rdmx6-jaaaa-aaaaa-aaadq-cai")
+	publicKey := identity.NewCanisterSigPublicKey(canisterId, []byte("seed")).ToDer()
+	message := []byte("\x1Aic-request-auth-delegation challenge")
+
+	parsed, err := identity.ParseCanisterSigPublicKey(publicKey)
+	assert.Nil(t, err)
+	assert.Equal(t, canisterId.ToBytes(), parsed.CanisterID.ToBytes())
+	assert.Equal(t, []byte("seed"), parsed.Seed)
+
+	seedHash := sha256.Sum256([]byte("seed"))
+	messageHash := sha256.Sum256(message)
+	sigTree := labeled([]byte("sig"), labeled(seedHash[:], labeled(messageHash[:], agent.HashTree{agent.Leaf, []byte{}})))
+	certifiedData, err := agent.Reconstruct(sigTree)
+	assert.Nil(t, err)
+	certTree := labeled([]byte("canister"), labeled(canisterId.ToBytes(), labeled([]byte("certified_data"), agent.HashTree{agent.Leaf, certifiedData})))
+
+	root := newBlsKey(1)
+	signature := func(certificate []byte) []byte {
+		data, err := cbor.Marshal(map[string]interface{}{"certificate": certificate, "tree": sigTree})
+		assert.Nil(t, err)
+		return append([]byte{0xd9, 0xd9, 0xf7}, data...)
+	}
+
+	t.Run("root", func(t *testing.T) {
+		sig := signature(root.certify(t, certTree, nil))
+		assert.Nil(t, agent.VerifyCanisterSignature(publicKey, message, sig, root.der()))
+		assert.NotNil(t, agent.VerifyCanisterSignature(publicKey, []byte("other"), sig, root.der()))
+		assert.NotNil(t, agent.VerifyCanisterSignature(publicKey, message, sig, newBlsKey(2).der()))
+
+		other := identity.NewCanisterSigPublicKey(canisterId, []byte("other seed")).ToDer()
+		assert.NotNil(t, agent.VerifyCanisterSignature(other, message, sig, root.der()))
+	})
+
+	t.Run("subnet delegation", func(t *testing.T) {
+		subnet := newBlsKey(3)
+		subnetId := []byte("subnet-id")
+		sign := func(ranges principal.CanisterRanges) []byte {
+			data, err := cbor.Marshal(ranges)
+			assert.Nil(t, err)
+			delegationTree := labeled([]byte("subnet"), labeled(subnetId, agent.HashTree{agent.Fork,
+				labeled([]byte("canister_ranges"), agent.HashTree{agent.Leaf, data}),
+				labeled([]byte("public_key"), agent.HashTree{agent.Leaf, subnet.der()}),
+			}))
+			delegation := &agent.Delegation{
+				SubnetId:    subnetId,
+				Certificate: root.certify(t, delegationTree, nil),
+			}
+			return signature(subnet.certify(t, certTree, delegation))
+		}
+		sig := sign(principal.CanisterRanges{
+			principal.NewCanisterRange(principal.FromCanisterIndex(0), principal.FromCanisterIndex(1<<20-1)),
+		})
+		assert.Nil(t, agent.VerifyCanisterSignature(publicKey, message, sig, root.der()))
+		assert.NotNil(t, agent.VerifyCanisterSignature(publicKey, message, sig, subnet.der()))
+
+		sig = sign(principal.CanisterRanges{
+			principal.NewCanisterRange(principal.FromCanisterIndex(1<<20), principal.FromCanisterIndex(2<<20-1)),
+		})
+		assert.NotNil(t, agent.VerifyCanisterSignature(publicKey, message, sig, root.der()))
+	})
+
+	t.Run("read state", func(t *testing.T) {
+		subnet := newBlsKey(3)
+		subnetId := []byte("subnet-id")
+		data, err := cbor.Marshal(principal.CanisterRanges{
+			principal.NewCanisterRange(principal.FromCanisterIndex(0), principal.FromCanisterIndex(1<<20-1)),
+		})
+		assert.Nil(t, err)
+		withRanges := labeled([]byte("subnet"), labeled(subnetId, agent.HashTree{agent.Fork,
+			labeled([]byte("canister_ranges"), agent.HashTree{agent.Leaf, data}),
+			labeled([]byte("public_key"), agent.HashTree{agent.Leaf, subnet.der()}),
+		}))
+		withoutRanges := labeled([]byte("subnet"), labeled(subnetId,
+			labeled([]byte("public_key"), agent.HashTree{agent.Leaf, subnet.der()}),
+		))
+		certificate := func(delegationTree agent.HashTree) []byte {
+			return subnet.certify(t, certTree, &agent.Delegation{
+				SubnetId:    subnetId,
+				Certificate: root.certify(t, delegationTree, nil),
+			})
+		}
+		verify := func(data []byte, canisterId *principal.Principal) bool {
+			cert, err := agent.ParseCertificate(data, root.der(), canisterId)
+			assert.Nil(t, err)
+			return cert.Verify()
+		}
+
+		assert.True(t, verify(certificate(withRanges), canisterId))
+		// A delegated certificate is not trusted for any canister.
+		assert.False(t, verify(certificate(withRanges), nil))
+		assert.False(t, verify(certificate(withRanges), principal.FromCanisterIndex(1<<20)))
+		assert.False(t, verify(certificate(withoutRanges), canisterId))
+		// Certificates signed by the root key need no canister id.
+		assert.True(t, verify(root.certify(t, certTree, nil), nil))
+	})
+
+	t.Run("non-empty leaf", func(t *testing.T) {
+		tree := labeled([]byte("sig"), labeled(seedHash[:], labeled(messageHash[:], agent.HashTree{agent.Leaf, []byte("x")})))
+		certifiedData, err := agent.Reconstruct(tree)
+		assert.Nil(t, err)
+		certTree := labeled([]byte("canister"), labeled(canisterId.ToBytes(), labeled([]byte("certified_data"), agent.HashTree{agent.Leaf, certifiedData})))
+		data, err := cbor.Marshal(map[string]interface{}{"certificate": root.certify(t, certTree, nil), "tree": tree})
+		assert.Nil(t, err)
+		assert.NotNil(t, agent.VerifyCanisterSignature(publicKey, message, data, root.der()))
+	})
+}
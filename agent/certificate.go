@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/fxamacker/cbor/v2"
+	"github.com/icpfans-xyz/agent-go/principal"
 	"github.com/icpfans-xyz/agent-go/principal/utils"
 )
 
@@ -30,6 +31,86 @@ const (
 
 type HashTree []interface{}
 
+// UnmarshalCBOR decodes a hash tree, converting node ids to NodeId and
+// subtrees to HashTree so that the tree can be traversed.
+func (t *HashTree) UnmarshalCBOR(data []byte) error {
+	var raw []interface{}
+	if err := cbor.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	tree, err := toHashTree(raw)
+	if err != nil {
+		return err
+	}
+	*t = tree
+	return nil
+}
+
+func toHashTree(raw []interface{}) (HashTree, error) {
+	if len(raw) == 0 {
+		return nil, errors.New("hash tree: empty node")
+	}
+	id, ok := raw[0].(uint64)
+	if !ok {
+		return nil, fmt.Errorf("hash tree: invalid node id: %v", raw[0])
+	}
+	switch NodeId(id) {
+	case Empty:
+		if len(raw) != 1 {
+			return nil, errors.New("hash tree: invalid empty node")
+		}
+		return HashTree{Empty}, nil
+	case Fork:
+		if len(raw) != 3 {
+			return nil, errors.New("hash tree: invalid fork node")
+		}
+		left, err := toSubtree(raw[1])
+		if err != nil {
+			return nil, err
+		}
+		right, err := toSubtree(raw[2])
+		if err != nil {
+			return nil, err
+		}
+		return HashTree{Fork, left, right}, nil
+	case Labeled:
+		if len(raw) != 3 {
+			return nil, errors.New("hash tree: invalid labeled node")
+		}
+		label, ok := raw[1].([]byte)
+		if !ok {
+			return nil, errors.New("hash tree: invalid label")
+		}
+		sub, err := toSubtree(raw[2])
+		if err != nil {
+			return nil, err
+		}
+		return HashTree{Labeled, label, sub}, nil
+	case Leaf, Pruned:
+		if len(raw) != 2 {
+			return nil, fmt.Errorf("hash tree: invalid node: %d", id)
+		}
+		bytes, ok := raw[1].([]byte)
+		if !ok {
+			return nil, errors.New("hash tree: invalid blob")
+		}
+		if NodeId(id) == Pruned && len(bytes) != 32 {
+			return nil, errors.New("hash tree: invalid pruned digest")
+		}
+		return HashTree{NodeId(id), bytes}, nil
+	default:
+		return nil, fmt.Errorf("hash tree: unknown node id: %d", id)
+	}
+}
+
+func toSubtree(v interface{}) (HashTree, error) {
+	raw, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("hash tree: invalid subtree: %v", v)
+	}
+	return toHashTree(raw)
+}
+
 func HashTreeToString(tree HashTree) string {
 	indentFunc := func(s string) string {
 		strs := strings.Split(s, "\n")
@@ -75,24 +156,40 @@ type Delegation struct {
 type Cert struct {
 	Tree HashTree `cbor:"tree"`
 
-	Signature  []byte     `cbor:"signature"`
-	Delegation Delegation `cbor:"delegation"`
+	Signature  []byte      `cbor:"signature"`
+	Delegation *Delegation `cbor:"delegation,omitempty"`
 }
 
 type Certificate struct {
 	cert     *Cert
 	verified bool
 	rootKey  []byte
+	// The canister the certificate is about, which must be in the canister
+	// ranges of the subnet of a delegation.
+	canisterId *principal.Principal
 }
 
-func NewCertificate(resp ReadStateResponse, agent Agent) (*Certificate, error) {
+// NewCertificate decodes the certificate of a read_state response for the
+// effective canister id of the request.
+func NewCertificate(resp ReadStateResponse, agent Agent, canisterId *principal.Principal) (*Certificate, error) {
+	return ParseCertificate(resp.Certificate, agent.RootKey(), canisterId)
+}
+
+// ParseCertificate decodes a CBOR certificate that is verified against the
+// given DER-encoded root key. A certificate signed by a subnet through a
+// delegation only verifies if the canister it is about, the effective
+// canister id of the request, is in the canister ranges of that subnet. The
+// canister id can only be nil for certificates signed by the root key.
+func ParseCertificate(data []byte, rootKey []byte, canisterId *principal.Principal) (*Certificate, error) {
 	var cert Cert
-	err := cbor.Unmarshal(resp.Certificate, &cert)
+	err := cbor.Unmarshal(data, &cert)
 	if err != nil {
 		return nil, err
 	}
 	return &Certificate{
-		cert: &cert,
+		cert:       &cert,
+		rootKey:    rootKey,
+		canisterId: canisterId,
 	}, nil
 }
 
@@ -103,74 +200,67 @@ func (c *Certificate) checkState() error {
 	return nil
 }
 
-func (c *Certificate) flattenForks(tree HashTree) []HashTree {
-	if tree[0] == Empty {
-		return []HashTree{}
-	} else if tree[0] == Fork {
-		return append(c.flattenForks(tree[1].(HashTree)), c.flattenForks(tree[2].(HashTree))...)
-	} else {
-		return []HashTree{tree}
+func (c *Certificate) Lookup(path [][]byte) ([]byte, error) {
+	if err := c.checkState(); err != nil {
+		return nil, err
 	}
+	return LookupPath(path, c.cert.Tree)
 }
 
-func (c *Certificate) findLabel(label []byte, trees []HashTree) (HashTree, error) {
-	if len(label) == 0 {
-		return nil, errors.New("undefined")
+func (c *Certificate) Verify() bool {
+	rootHash, err := Reconstruct(c.cert.Tree)
+	if err != nil {
+		return false
 	}
-	for _, tree := range trees {
-		if tree[0] == Labeled {
-			if bytes.Equal(label, tree[1].([]byte)) {
-				return tree, nil
-			}
-		}
+	derKey, err := c.checkDelegation(c.cert.Delegation)
+	if err != nil {
+		return false
 	}
-	return nil, errors.New("undefined")
+	key, err := ExtractDER(derKey)
+	if err != nil {
+		return false
+	}
+	msg := append(domainSep("ic-state-root"), rootHash...)
+	c.verified = blsVerify(key, c.cert.Signature, msg)
+	return c.verified
 }
 
-func (c *Certificate) lookupPath(path [][]byte, tree HashTree) ([]byte, error) {
-	if len(path) == 0 {
-		if tree[0] == Leaf {
-			return tree[1].([]byte), nil
-		} else {
-			return nil, errors.New("undefined")
+// checkDelegation returns the DER-encoded key that signed the certificate:
+// the root key, or the subnet key certified by the delegation.
+func (c *Certificate) checkDelegation(d *Delegation) ([]byte, error) {
+	if d == nil {
+		if len(c.rootKey) == 0 {
+			return nil, errors.New("missing root key")
 		}
+		return c.rootKey, nil
+	}
+	// Without the range check, the key of any subnet could certify the state
+	// of any canister.
+	if c.canisterId == nil {
+		return nil, errors.New("a canister id is required to verify a delegated certificate")
 	}
-	label := path[0]
-	t, err := c.findLabel(label, c.flattenForks(tree))
+	cert, err := ParseCertificate(d.Certificate, c.rootKey, nil)
 	if err != nil {
 		return nil, err
 	}
-	if t != nil {
-		return c.lookupPath(path[1:], t)
+	if cert.cert.Delegation != nil {
+		return nil, errors.New("certificate delegations must not be nested")
 	}
-
-	return nil, nil
-}
-
-func (c *Certificate) Lookup(path [][]byte) ([]byte, error) {
-	if err := c.checkState(); err != nil {
+	if !cert.Verify() {
+		return nil, errors.New("fail to verify delegation certificate")
+	}
+	data, err := cert.Lookup([][]byte{[]byte("subnet"), d.SubnetId, []byte("canister_ranges")})
+	if err != nil {
+		return nil, errors.New("delegation certificate does not contain canister ranges")
+	}
+	ranges, err := principal.ParseCanisterRanges(data)
+	if err != nil {
 		return nil, err
 	}
-	return c.lookupPath(path, c.cert.Tree)
-}
-
-func (c *Certificate) Verify() bool {
-	// root, err := Reconstruct(c.cert.Tree)
-	// if err != nil {
-	// 	return false
-	// }
-
-	//TODO: verify root bls
-	// const rootHash = await reconstruct(this.cert.tree);
-	// const derKey = await this._checkDelegation(this.cert.delegation);
-	// const sig = this.cert.signature;
-	// const key = extractDER(derKey);
-	// const msg = concat(domain_sep('ic-state-root'), rootHash);
-	// const res = await blsVerify(new Uint8Array(key), new Uint8Array(sig), new Uint8Array(msg));
-	// this.verified = res;
-
-	c.verified = true
-	return true
+	if !ranges.Contains(c.canisterId) {
+		return nil, fmt.Errorf("canister %s is not in the canister ranges of the subnet", c.canisterId)
+	}
+	return cert.Lookup([][]byte{[]byte("subnet"), d.SubnetId, []byte("public_key")})
 }
 
 var (
@@ -183,11 +273,11 @@ func ExtractDER(der []byte) ([]byte, error) {
 	prefixLen := len(DER_PREFIX)
 	expectedLength := prefixLen + KEY_LENGTH
 	if expectedLength != len(der) {
-		return nil, errors.New("BLS DER-encoded public key must be ${expectedLength} bytes long")
+		return nil, fmt.Errorf("BLS DER-encoded public key must be %d bytes long", expectedLength)
 	}
 	prefix := der[:prefixLen]
-	if bytes.Equal(prefix, DER_PREFIX) {
-		return nil, errors.New("BLS DER-encoded public key is invalid. Expect the following prefix: ${DER_PREFIX}, but get ${prefix}")
+	if !bytes.Equal(prefix, DER_PREFIX) {
+		return nil, fmt.Errorf("BLS DER-encoded public key is invalid. Expect the following prefix: %x, but get %x", DER_PREFIX, prefix)
 	}
 	return der[prefixLen:], nil
 }
@@ -222,7 +312,7 @@ func Reconstruct(tree HashTree) ([]byte, error) {
 }
 
 func domainSep(s string) []byte {
-	return append(utils.FromUint32(uint32(len(s))), []byte(s)...)
+	return append([]byte{byte(len(s))}, []byte(s)...)
 }
 
 func LookupPath(path [][]byte, t HashTree) ([]byte, error) {
@@ -230,7 +320,6 @@ func LookupPath(path [][]byte, t HashTree) ([]byte, error) {
 		if t[0] == Leaf {
 			return t[1].([]byte), nil
 		}
-	} else {
 		return nil, errors.New("undefined")
 	}
 	ts, err := flattenForks(t)
@@ -269,7 +358,7 @@ func findLabel(l []byte, ts []HashTree) (HashTree, error) {
 	for _, t := range ts {
 		if t[0] == Labeled {
 			if bytes.Equal(l, t[1].([]byte)) {
-				return t[1].(HashTree), nil
+				return t[2].(HashTree), nil
 			}
 		}
 	}
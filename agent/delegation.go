@@ -0,0 +1,282 @@
+package agent
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/icpfans-xyz/agent-go/identity"
+	"github.com/icpfans-xyz/agent-go/principal"
+)
+
+var DelegationDomainSeparator = []byte("\x1Aic-request-auth-delegation")
+
+// MaxDelegations is the maximum length of a delegation chain accepted by the
+// IC.
+const MaxDelegations = 20
+
+var (
+	pubkeyKey     = sha256.Sum256([]byte("pubkey"))
+	expirationKey = sha256.Sum256([]byte("expiration"))
+	targetsKey    = sha256.Sum256([]byte("targets"))
+)
+
+// DOCS: https://smartcontracts.org/docs/interface-spec/index.html#authentication
+type SenderDelegation struct {
+	// The DER-encoded public key the authority is delegated to.
+	Pubkey []byte `cbor:"pubkey"`
+	// Expiration of the delegation, in nanoseconds since 1970-01-01.
+	Expiration uint64 `cbor:"expiration"`
+	// If set, the delegation is only valid for calls to these canisters.
+	Targets [][]byte `cbor:"targets,omitempty"`
+}
+
+type SignedDelegation struct {
+	Delegation SenderDelegation `cbor:"delegation"`
+	Signature  []byte           `cbor:"signature"`
+}
+
+// DelegationChain is a chain of delegations starting at PublicKey, where each
+// delegation is signed by the key of the previous one.
+type DelegationChain struct {
+	Delegations []SignedDelegation
+	// The DER-encoded public key of the root of the chain.
+	PublicKey []byte
+}
+
+// HashOf returns the representation-independent hash of the delegation.
+func (d SenderDelegation) HashOf() [32]byte {
+	var (
+		pubkeyHash     = sha256.Sum256(d.Pubkey)
+		expirationHash = sha256.Sum256(encodeLEB128(d.Expiration))
+	)
+	hashes := [][]byte{
+		append(pubkeyKey[:], pubkeyHash[:]...),
+		append(expirationKey[:], expirationHash[:]...),
+	}
+	if d.Targets != nil {
+		targetsHash := encodeList2D(d.Targets)
+		hashes = append(hashes, append(targetsKey[:], targetsHash[:]...))
+	}
+	sort.Slice(hashes, func(i, j int) bool {
+		return bytes.Compare(hashes[i], hashes[j]) == -1
+	})
+	return sha256.Sum256(bytes.Join(hashes, nil))
+}
+
+// NewDelegationChain creates a delegation from the key pair to the public
+// key. If previous is not nil, the delegation is appended to it, so the key
+// pair must be the target of the last delegation of previous.
+func NewDelegationChain(from identity.KeyPair, to identity.PublicKey, expiration time.Time, targets []*principal.Principal, previous *DelegationChain) (*DelegationChain, error) {
+	delegation := SenderDelegation{
+		Pubkey:     to.ToDer(),
+		Expiration: uint64(expiration.UnixNano()),
+	}
+	for _, t := range targets {
+		delegation.Targets = append(delegation.Targets, t.ToBytes())
+	}
+	hash := delegation.HashOf()
+	signature, err := from.Sign(append(DelegationDomainSeparator, hash[:]...))
+	if err != nil {
+		return nil, err
+	}
+	chain := &DelegationChain{
+		PublicKey: from.PublicKey().ToDer(),
+	}
+	if previous != nil {
+		chain.PublicKey = previous.PublicKey
+		chain.Delegations = append(chain.Delegations, previous.Delegations...)
+	}
+	chain.Delegations = append(chain.Delegations, SignedDelegation{
+		Delegation: delegation,
+		Signature:  signature,
+	})
+	return chain, nil
+}
+
+// SessionKey returns the DER-encoded public key at the end of the chain.
+func (c *DelegationChain) SessionKey() []byte {
+	if len(c.Delegations) == 0 {
+		return c.PublicKey
+	}
+	return c.Delegations[len(c.Delegations)-1].Delegation.Pubkey
+}
+
+// Verify checks the signature of every delegation in the chain and that none
+// of them is expired at the given time. The DER-encoded root key is used for
+// delegations signed with canister signatures (e.g. by Internet Identity).
+func (c *DelegationChain) Verify(now time.Time, rootKey []byte) error {
+	if len(c.Delegations) > MaxDelegations {
+		return fmt.Errorf("too many delegations: %d, the maximum is %d", len(c.Delegations), MaxDelegations)
+	}
+	signer := c.PublicKey
+	for i, d := range c.Delegations {
+		if d.Delegation.Expiration < uint64(now.UnixNano()) {
+			return fmt.Errorf("delegation %d expired", i)
+		}
+		hash := d.Delegation.HashOf()
+		if err := verifySignature(signer, append(DelegationDomainSeparator, hash[:]...), d.Signature, rootKey); err != nil {
+			return fmt.Errorf("delegation %d: %v", i, err)
+		}
+		signer = d.Delegation.Pubkey
+	}
+	return nil
+}
+
+// IsValidTarget reports whether every delegation in the chain allows calls
+// to the canister.
+func (c *DelegationChain) IsValidTarget(canisterId *principal.Principal) bool {
+	for _, d := range c.Delegations {
+		if d.Delegation.Targets == nil {
+			continue
+		}
+		found := false
+		for _, t := range d.Delegation.Targets {
+			if bytes.Equal(t, canisterId.ToBytes()) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// delegationChainJSON mirrors the JSON format used by agent-js, which hex
+// encodes byte strings and the expiration.
+type delegationChainJSON struct {
+	Delegations []signedDelegationJSON `json:"delegations"`
+	PublicKey   string                 `json:"publicKey"`
+}
+
+type signedDelegationJSON struct {
+	Delegation delegationJSON `json:"delegation"`
+	Signature  string         `json:"signature"`
+}
+
+type delegationJSON struct {
+	Pubkey     string   `json:"pubkey"`
+	Expiration string   `json:"expiration"`
+	Targets    []string `json:"targets,omitempty"`
+}
+
+func (c DelegationChain) MarshalJSON() ([]byte, error) {
+	v := delegationChainJSON{
+		Delegations: []signedDelegationJSON{},
+		PublicKey:   hex.EncodeToString(c.PublicKey),
+	}
+	for _, d := range c.Delegations {
+		sd := signedDelegationJSON{
+			Delegation: delegationJSON{
+				Pubkey:     hex.EncodeToString(d.Delegation.Pubkey),
+				Expiration: new(big.Int).SetUint64(d.Delegation.Expiration).Text(16),
+			},
+			Signature: hex.EncodeToString(d.Signature),
+		}
+		for _, t := range d.Delegation.Targets {
+			sd.Delegation.Targets = append(sd.Delegation.Targets, hex.EncodeToString(t))
+		}
+		v.Delegations = append(v.Delegations, sd)
+	}
+	return json.Marshal(v)
+}
+
+func (c *DelegationChain) UnmarshalJSON(data []byte) error {
+	var v delegationChainJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	publicKey, err := hex.DecodeString(v.PublicKey)
+	if err != nil {
+		return err
+	}
+	var delegations []SignedDelegation
+	for _, d := range v.Delegations {
+		var sd SignedDelegation
+		if sd.Signature, err = hex.DecodeString(d.Signature); err != nil {
+			return err
+		}
+		if sd.Delegation.Pubkey, err = hex.DecodeString(d.Delegation.Pubkey); err != nil {
+			return err
+		}
+		expiration, ok := new(big.Int).SetString(d.Delegation.Expiration, 16)
+		if !ok || !expiration.IsUint64() {
+			return errors.New("invalid delegation expiration: " + d.Delegation.Expiration)
+		}
+		sd.Delegation.Expiration = expiration.Uint64()
+		for _, t := range d.Delegation.Targets {
+			target, err := hex.DecodeString(t)
+			if err != nil {
+				return err
+			}
+			sd.Delegation.Targets = append(sd.Delegation.Targets, target)
+		}
+		delegations = append(delegations, sd)
+	}
+	c.PublicKey = publicKey
+	c.Delegations = delegations
+	return nil
+}
+
+// DelegationIdentity signs requests with a session key on behalf of the
+// principal at the root of a delegation chain.
+type DelegationIdentity struct {
+	key       identity.KeyPair
+	chain     *DelegationChain
+	principal *principal.Principal
+}
+
+// NewDelegationIdentity creates an identity that signs with the key, which
+// must be the session key at the end of the chain.
+func NewDelegationIdentity(key identity.KeyPair, chain *DelegationChain) (*DelegationIdentity, error) {
+	if !bytes.Equal(key.PublicKey().ToDer(), chain.SessionKey()) {
+		return nil, errors.New("key is not the session key of the delegation chain")
+	}
+	return &DelegationIdentity{
+		key:   key,
+		chain: chain,
+	}, nil
+}
+
+func (d *DelegationIdentity) GetDelegation() *DelegationChain {
+	return d.chain
+}
+
+func (d *DelegationIdentity) Sign(blob []byte) (Signature, error) {
+	return d.key.Sign(blob)
+}
+
+func (d *DelegationIdentity) GetPrincipal() *principal.Principal {
+	if d.principal == nil {
+		principal, err := principal.SelfAuthenticating(d.chain.PublicKey)
+		if err != nil {
+			panic(err)
+		}
+		d.principal = principal
+	}
+	return d.principal
+}
+
+func (d *DelegationIdentity) TransformRequest(request Request) (*TransformRequest, error) {
+	requestId := RequestIdOf(request)
+	sign, err := d.Sign(append(DomainSeparator, requestId[:]...))
+	if err != nil {
+		return nil, err
+	}
+	return &TransformRequest{
+		Body: SignTransformBody{
+			Content:          request,
+			SenderPubkey:     d.chain.PublicKey,
+			SenderSig:        sign,
+			SenderDelegation: d.chain.Delegations,
+		},
+	}, nil
+}
@@ -0,0 +1,129 @@
+package agent_test
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/icpfans-xyz/agent-go/agent"
+	"github.com/icpfans-xyz/agent-go/identity"
+	"github.com/icpfans-xyz/agent-go/principal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDelegationChain(t *testing.T) {
+	seed, _ := hex.DecodeString("833fe62409237b9d62ec77587520911e9a759cec1d19755b7da901b96dca3d42")
+	root := identity.NewEd25519Identity(seed)
+	middle, err := identity.GeneratePrime256v1Identity()
+	assert.Nil(t, err)
+	session, err := identity.GenerateSecp256k1Identity()
+	assert.Nil(t, err)
+	canisterId, _ := principal.FromString("rrkah-fqaaa-aaaaa-aaaaq-cai")
+	expiration := time.Now().Add(time.Hour)
+
+	first, err := agent.NewDelegationChain(root, middle.PublicKey(), expiration, nil, nil)
+	assert.Nil(t, err)
+	chain, err := agent.NewDelegationChain(middle, session.PublicKey(), expiration, []*principal.Principal{canisterId}, first)
+	assert.Nil(t, err)
+	assert.Len(t, chain.Delegations, 2)
+	assert.Equal(t, root.PublicKey().ToDer(), chain.PublicKey)
+	assert.Equal(t, session.PublicKey().ToDer(), chain.SessionKey())
+
+	t.Run("verify", func(t *testing.T) {
+		assert.Nil(t, chain.Verify(time.Now(), nil))
+		assert.NotNil(t, chain.Verify(expiration.Add(time.Second), nil))
+		assert.True(t, chain.IsValidTarget(canisterId))
+		assert.False(t, chain.IsValidTarget(principal.NewPrincipal([]byte{0x04})))
+
+		tampered := *chain
+		tampered.Delegations = append([]agent.SignedDelegation{}, chain.Delegations...)
+		tampered.Delegations[1].Delegation.Expiration++
+		assert.NotNil(t, tampered.Verify(time.Now(), nil))
+	})
+
+	t.Run("max length", func(t *testing.T) {
+		long := first
+		for i := 1; i <= agent.MaxDelegations; i++ {
+			long, err = agent.NewDelegationChain(middle, middle.PublicKey(), expiration, nil, long)
+			assert.Nil(t, err)
+			if i < agent.MaxDelegations {
+				assert.Nil(t, long.Verify(time.Now(), nil))
+			}
+		}
+		assert.Len(t, long.Delegations, agent.MaxDelegations+1)
+		assert.NotNil(t, long.Verify(time.Now(), nil))
+	})
+
+	t.Run("session key", func(t *testing.T) {
+		_, err := agent.NewDelegationIdentity(middle, chain)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		data, err := json.Marshal(chain)
+		assert.Nil(t, err)
+		var decoded agent.DelegationChain
+		assert.Nil(t, json.Unmarshal(data, &decoded))
+		assert.Equal(t, *chain, decoded)
+	})
+
+	t.Run("transform", func(t *testing.T) {
+		id, err := agent.NewDelegationIdentity(session, chain)
+		assert.Nil(t, err)
+		expected, _ := principal.SelfAuthenticating(root.PublicKey().ToDer())
+		assert.Equal(t, expected, id.GetPrincipal())
+
+		request := agent.Request{
+			Type:       "call",
+			Sender:     id.GetPrincipal().ToBytes(),
+			CanisterID: canisterId.ToBytes(),
+			MethodName: "greet",
+			Arguments:  []byte("DIDL\x00\x00"),
+		}
+		transformed, err := id.TransformRequest(request)
+		assert.Nil(t, err)
+		data, err := cbor.Marshal(transformed.Body)
+		assert.Nil(t, err)
+
+		var envelope struct {
+			SenderPubkey     []byte                   `cbor:"sender_pubkey"`
+			SenderSig        []byte                   `cbor:"sender_sig"`
+			SenderDelegation []agent.SignedDelegation `cbor:"sender_delegation"`
+		}
+		assert.Nil(t, cbor.Unmarshal(data, &envelope))
+		assert.Equal(t, chain.PublicKey, envelope.SenderPubkey)
+		assert.Equal(t, chain.Delegations, envelope.SenderDelegation)
+		requestId := agent.RequestIdOf(request)
+		assert.Nil(t, identity.Verify(chain.SessionKey(), append(agent.DomainSeparator, requestId[:]...), envelope.SenderSig))
+	})
+
+	t.Run("envelope", func(t *testing.T) {
+		id, err := agent.NewDelegationIdentity(session, chain)
+		assert.Nil(t, err)
+		request := agent.Request{
+			Type:       "call",
+			Sender:     id.GetPrincipal().ToBytes(),
+			CanisterID: canisterId.ToBytes(),
+			MethodName: "greet",
+		}
+		transformed, err := id.TransformRequest(request)
+		assert.Nil(t, err)
+		body := transformed.Body.(agent.SignTransformBody)
+		assert.Nil(t, body.Verify(time.Now(), nil))
+		assert.NotNil(t, body.Verify(expiration.Add(time.Second), nil))
+
+		other := body
+		other.Content.CanisterID = []byte{0x04}
+		assert.NotNil(t, other.Verify(time.Now(), nil))
+
+		other = body
+		other.Content.Sender = []byte{0x04}
+		assert.NotNil(t, other.Verify(time.Now(), nil))
+
+		other = body
+		other.Content.MethodName = "other"
+		assert.NotNil(t, other.Verify(time.Now(), nil))
+	})
+}
@@ -3,6 +3,7 @@ package http
 import (
 	"bytes"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -79,7 +80,12 @@ type HttpAgent struct {
 }
 
 func NewHttpAgent(options HttpAgentOptions) (*HttpAgent, error) {
+	rootKey, err := hex.DecodeString(IC_ROOT_KEY)
+	if err != nil {
+		return nil, err
+	}
 	hagent := &HttpAgent{
+		rootKey:  rootKey,
 		pipeline: []HttpAgentRequestTransform{},
 	}
 	if options.Source != nil {
@@ -151,12 +157,25 @@ func (a *HttpAgent) ReadState(canisterId *principal.Principal, options *agent.Re
 	if err != nil {
 		return nil, err
 	}
-	body, err := cbor.Marshal(transformRequest)
+	body, err := cbor.Marshal(transformRequest.Body)
 	if err != nil {
 		return nil, err
 	}
+	return a.readState(canisterId, request.HttpRequest, body)
+}
+
+// ReadStateSigned submits a read_state envelope signed ahead of time, see
+// agent.SignCall.
+func (a *HttpAgent) ReadStateSigned(signed *agent.SignedRequest) (*agent.ReadStateResponse, error) {
+	if signed.RequestType != RequestTypeReadState {
+		return nil, fmt.Errorf("expected a %s request, got %s", RequestTypeReadState, signed.RequestType)
+	}
+	return a.readState(signed.EffectiveCanisterId, a.newHttpRequest(), signed.Envelope)
+}
+
+func (a *HttpAgent) readState(canisterId *principal.Principal, request *HttpRequest, body []byte) (*agent.ReadStateResponse, error) {
 	path := fmt.Sprintf("/api/v2/canister/%s/read_state", canisterId.ToString())
-	resp, err := a.fetch(path, request.HttpRequest, body)
+	resp, err := a.fetch(path, request, body)
 	if err != nil {
 		return nil, err
 	}
@@ -168,6 +187,17 @@ func (a *HttpAgent) ReadState(canisterId *principal.Principal, options *agent.Re
 	return &response, nil
 }
 
+func (a *HttpAgent) newHttpRequest() *HttpRequest {
+	request := &HttpRequest{
+		Method:  "POST",
+		Headers: map[string]string{"Content-Type": "application/cbor"},
+	}
+	if len(a.credentials) > 0 {
+		request.Headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(a.credentials))
+	}
+	return request
+}
+
 func (a *HttpAgent) fetch(path string, request *HttpRequest, body []byte) ([]byte, error) {
 	client := &http.Client{}
 	url := a.host + path
@@ -225,8 +255,21 @@ func (a *HttpAgent) Call(canisterId *principal.Principal, options *agent.CallOpt
 	if err != nil {
 		return nil, err
 	}
-	path := fmt.Sprintf("/api/v2/canister/%s/call", ecid.ToString())
-	resp, err := a.fetch(path, request.HttpRequest, body)
+	return a.call(ecid, request.HttpRequest, body, agent.RequestIdOf(submit))
+}
+
+// SubmitSigned submits a call envelope signed ahead of time as-is, see
+// agent.SignCall. Its status can be polled with ReadStateSigned.
+func (a *HttpAgent) SubmitSigned(signed *agent.SignedRequest) (*agent.SubmitResponse, error) {
+	if signed.RequestType != RequestTypeCall {
+		return nil, fmt.Errorf("expected a %s request, got %s", RequestTypeCall, signed.RequestType)
+	}
+	return a.call(signed.EffectiveCanisterId, a.newHttpRequest(), signed.Envelope, signed.RequestId)
+}
+
+func (a *HttpAgent) call(canisterId *principal.Principal, request *HttpRequest, body []byte, requestId agent.RequestId) (*agent.SubmitResponse, error) {
+	path := fmt.Sprintf("/api/v2/canister/%s/call", canisterId.ToString())
+	resp, err := a.fetch(path, request, body)
 	if err != nil {
 		return nil, err
 	}
@@ -238,8 +281,6 @@ func (a *HttpAgent) Call(canisterId *principal.Principal, options *agent.CallOpt
 	if !response.OK {
 		return nil, xerrors.Errorf("Server returned an error:%d,%s", response.Status, response.StatusText)
 	}
-	requestId := agent.RequestIdOf(submit)
-
 	return &agent.SubmitResponse{
 		RequestId: requestId,
 		Response:  response,
@@ -317,6 +358,7 @@ func (a *HttpAgent) FetchRootKey() ([]byte, error) {
 			return nil, err
 		}
 		a.rootKey = bytes
+		a.rootKeyFetched = true
 	}
 	return a.rootKey, nil
 }
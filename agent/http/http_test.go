@@ -2,11 +2,16 @@ package http_test
 
 import (
 	"encoding/hex"
+	"io/ioutil"
+	nethttp "net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/icpfans-xyz/agent-go/agent"
 	"github.com/icpfans-xyz/agent-go/identity"
 
+	"github.com/fxamacker/cbor/v2"
 	"github.com/icpfans-xyz/agent-go/agent/http"
 	"github.com/icpfans-xyz/agent-go/candid/idl"
 	"github.com/icpfans-xyz/agent-go/principal"
@@ -85,3 +90,39 @@ func TestAgentQuery(t *testing.T) {
 	assert.NotNil(t, resp)
 
 }
+
+func TestAgentSubmitSigned(t *testing.T) {
+	pbBytes, _ := hex.DecodeString("833fe62409237b9d62ec77587520911e9a759cec1d19755b7da901b96dca3d42")
+	id := agent.NewSignIdentity(identity.NewEd25519Identity(pbBytes), nil)
+	canisterId, _ := principal.FromString("rrkah-fqaaa-aaaaa-aaaaq-cai")
+	signed, err := agent.SignCall(id, agent.Request{
+		CanisterID:    canisterId.ToBytes(),
+		MethodName:    "greet",
+		IngressExpiry: uint64(time.Now().Add(time.Minute).UnixNano()),
+	}, nil)
+	assert.Nil(t, err)
+
+	received := map[string][]byte{}
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		received[r.URL.Path] = body
+		resp, _ := cbor.Marshal(map[string]interface{}{"OK": true, "Certificate": []byte{0x01}})
+		w.Write(resp)
+	}))
+	defer server.Close()
+	httpAgent, err := http.NewHttpAgent(http.HttpAgentOptions{Host: server.URL})
+	assert.Nil(t, err)
+
+	resp, err := httpAgent.SubmitSigned(&signed.Call)
+	assert.Nil(t, err)
+	assert.Equal(t, signed.Call.RequestId, resp.RequestId)
+	assert.Equal(t, signed.Call.Envelope, received["/api/v2/canister/rrkah-fqaaa-aaaaa-aaaaq-cai/call"])
+
+	state, err := httpAgent.ReadStateSigned(&signed.ReadState)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x01}, state.Certificate)
+	assert.Equal(t, signed.ReadState.Envelope, received["/api/v2/canister/rrkah-fqaaa-aaaaa-aaaaq-cai/read_state"])
+
+	_, err = httpAgent.SubmitSigned(&signed.ReadState)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,199 @@
+package agent
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/icpfans-xyz/agent-go/principal"
+)
+
+// SignedCallVersion is the version of the file format of signed calls.
+const SignedCallVersion = 1
+
+const (
+	requestTypeCall      RequestType = "call"
+	requestTypeReadState RequestType = "read_state"
+)
+
+// SignedRequest is a request whose CBOR envelope has been signed ahead of
+// time, so that it can be submitted as-is later and from another machine.
+type SignedRequest struct {
+	RequestType         RequestType
+	RequestId           RequestId
+	EffectiveCanisterId *principal.Principal
+	IngressExpiry       uint64
+	// The CBOR-encoded envelope, containing the content and the signature.
+	Envelope []byte
+}
+
+// SignedCall is a signed update call together with the signed read_state
+// request used to poll its status.
+type SignedCall struct {
+	Call      SignedRequest
+	ReadState SignedRequest
+}
+
+// SignRequest signs the request with the identity and encodes the envelope,
+// without any network access. The sender of the request defaults to the
+// principal of the identity, and the effective canister ID to the canister of
+// the request.
+func SignRequest(id Identity, request Request, effectiveCanisterId *principal.Principal) (*SignedRequest, error) {
+	if request.IngressExpiry == 0 {
+		return nil, errors.New("ingress expiry must be set")
+	}
+	if request.Sender == nil {
+		request.Sender = id.GetPrincipal().ToBytes()
+	}
+	if effectiveCanisterId == nil {
+		if request.CanisterID == nil {
+			return nil, errors.New("effective canister id must be set")
+		}
+		effectiveCanisterId = principal.NewPrincipal(request.CanisterID)
+	}
+	transformed, err := id.TransformRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	envelope, err := cbor.Marshal(transformed.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &SignedRequest{
+		RequestType:         request.Type,
+		RequestId:           RequestIdOf(request),
+		EffectiveCanisterId: effectiveCanisterId,
+		IngressExpiry:       request.IngressExpiry,
+		Envelope:            envelope,
+	}, nil
+}
+
+// SignCall signs an update call and the read_state request for its status.
+// Both requests share the ingress expiry of the call, so the status must be
+// polled before it expires.
+func SignCall(id Identity, request Request, effectiveCanisterId *principal.Principal) (*SignedCall, error) {
+	request.Type = requestTypeCall
+	call, err := SignRequest(id, request, effectiveCanisterId)
+	if err != nil {
+		return nil, err
+	}
+	readState, err := SignRequest(id, Request{
+		Type:          requestTypeReadState,
+		Sender:        request.Sender,
+		IngressExpiry: request.IngressExpiry,
+		Paths:         [][][]byte{{[]byte("request_status"), call.RequestId[:]}},
+	}, call.EffectiveCanisterId)
+	if err != nil {
+		return nil, err
+	}
+	return &SignedCall{
+		Call:      *call,
+		ReadState: *readState,
+	}, nil
+}
+
+// Content decodes the content of the envelope and checks that it matches
+// the request ID.
+func (s *SignedRequest) Content() (*Request, error) {
+	var envelope struct {
+		Content Request `cbor:"content"`
+	}
+	if err := cbor.Unmarshal(s.Envelope, &envelope); err != nil {
+		return nil, err
+	}
+	if RequestIdOf(envelope.Content) != s.RequestId {
+		return nil, errors.New("envelope does not match the request id")
+	}
+	if envelope.Content.Type != s.RequestType {
+		return nil, fmt.Errorf("envelope has request type %s, expected %s", envelope.Content.Type, s.RequestType)
+	}
+	return &envelope.Content, nil
+}
+
+// signedCallJSON is the portable file format of signed calls, which hex
+// encodes byte strings.
+type signedCallJSON struct {
+	Version   int               `json:"version"`
+	Call      signedRequestJSON `json:"call"`
+	ReadState signedRequestJSON `json:"read_state"`
+}
+
+type signedRequestJSON struct {
+	RequestType         string `json:"request_type"`
+	RequestId           string `json:"request_id"`
+	EffectiveCanisterId string `json:"effective_canister_id"`
+	IngressExpiry       uint64 `json:"ingress_expiry"`
+	Envelope            string `json:"envelope"`
+}
+
+func (s SignedCall) MarshalJSON() ([]byte, error) {
+	return json.Marshal(signedCallJSON{
+		Version:   SignedCallVersion,
+		Call:      s.Call.toJSON(),
+		ReadState: s.ReadState.toJSON(),
+	})
+}
+
+// UnmarshalJSON decodes a signed call and checks that both envelopes match
+// their request IDs.
+func (s *SignedCall) UnmarshalJSON(data []byte) error {
+	var v signedCallJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if v.Version != SignedCallVersion {
+		return fmt.Errorf("unsupported signed call version %d", v.Version)
+	}
+	call, err := v.Call.toSignedRequest()
+	if err != nil {
+		return err
+	}
+	readState, err := v.ReadState.toSignedRequest()
+	if err != nil {
+		return err
+	}
+	s.Call = *call
+	s.ReadState = *readState
+	return nil
+}
+
+func (s *SignedRequest) toJSON() signedRequestJSON {
+	return signedRequestJSON{
+		RequestType:         s.RequestType,
+		RequestId:           hex.EncodeToString(s.RequestId[:]),
+		EffectiveCanisterId: s.EffectiveCanisterId.ToString(),
+		IngressExpiry:       s.IngressExpiry,
+		Envelope:            hex.EncodeToString(s.Envelope),
+	}
+}
+
+func (v signedRequestJSON) toSignedRequest() (*SignedRequest, error) {
+	requestId, err := hex.DecodeString(v.RequestId)
+	if err != nil {
+		return nil, err
+	}
+	if len(requestId) != len(RequestId{}) {
+		return nil, errors.New("invalid request id: " + v.RequestId)
+	}
+	effectiveCanisterId, err := principal.FromString(v.EffectiveCanisterId)
+	if err != nil {
+		return nil, err
+	}
+	envelope, err := hex.DecodeString(v.Envelope)
+	if err != nil {
+		return nil, err
+	}
+	s := &SignedRequest{
+		RequestType:         v.RequestType,
+		EffectiveCanisterId: effectiveCanisterId,
+		IngressExpiry:       v.IngressExpiry,
+		Envelope:            envelope,
+	}
+	copy(s.RequestId[:], requestId)
+	if _, err := s.Content(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
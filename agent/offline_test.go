@@ -0,0 +1,72 @@
+package agent_test
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/icpfans-xyz/agent-go/agent"
+	"github.com/icpfans-xyz/agent-go/identity"
+	"github.com/icpfans-xyz/agent-go/principal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignCall(t *testing.T) {
+	seed, _ := hex.DecodeString("833fe62409237b9d62ec77587520911e9a759cec1d19755b7da901b96dca3d42")
+	id := agent.NewSignIdentity(identity.NewEd25519Identity(seed), nil)
+	canisterId, _ := principal.FromString("rrkah-fqaaa-aaaaa-aaaaq-cai")
+	request := agent.Request{
+		CanisterID:    canisterId.ToBytes(),
+		MethodName:    "greet",
+		Arguments:     []byte("DIDL\x00\x00"),
+		IngressExpiry: uint64(time.Now().Add(time.Minute).UnixNano()),
+	}
+	signed, err := agent.SignCall(id, request, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, canisterId, signed.Call.EffectiveCanisterId)
+	assert.Equal(t, canisterId, signed.ReadState.EffectiveCanisterId)
+
+	t.Run("envelope", func(t *testing.T) {
+		content, err := signed.Call.Content()
+		assert.Nil(t, err)
+		assert.Equal(t, "call", content.Type)
+		assert.Equal(t, id.GetPrincipal().ToBytes(), content.Sender)
+		assert.Equal(t, signed.Call.RequestId, agent.RequestIdOf(*content))
+
+		content, err = signed.ReadState.Content()
+		assert.Nil(t, err)
+		assert.Equal(t, "read_state", content.Type)
+		assert.Equal(t, [][][]byte{{[]byte("request_status"), signed.Call.RequestId[:]}}, content.Paths)
+
+		for _, s := range []agent.SignedRequest{signed.Call, signed.ReadState} {
+			var body agent.SignTransformBody
+			assert.Nil(t, cbor.Unmarshal(s.Envelope, &body))
+			assert.Nil(t, body.Verify(time.Now(), nil))
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		data, err := json.Marshal(signed)
+		assert.Nil(t, err)
+		var decoded agent.SignedCall
+		assert.Nil(t, json.Unmarshal(data, &decoded))
+		assert.Equal(t, *signed, decoded)
+
+		tampered := *signed
+		tampered.Call.RequestId[0] ^= 0xff
+		data, err = json.Marshal(tampered)
+		assert.Nil(t, err)
+		assert.NotNil(t, json.Unmarshal(data, &decoded))
+
+		assert.NotNil(t, json.Unmarshal([]byte(`{"version":2}`), &decoded))
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		request := request
+		request.IngressExpiry = 0
+		_, err := agent.SignCall(id, request, nil)
+		assert.NotNil(t, err)
+	})
+}
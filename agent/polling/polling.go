@@ -31,7 +31,7 @@ func PollForResponse(agentimpl agent.Agent, canisterId *principal.Principal, req
 	if err != nil {
 		return nil, err
 	}
-	cert, err := agent.NewCertificate(*state, agentimpl)
+	cert, err := agent.NewCertificate(*state, agentimpl, canisterId)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,32 @@
+package candid
+
+import (
+	"fmt"
+
+	"github.com/icpfans-xyz/agent-go/candid/idl"
+)
+
+// ParseError is an error in a .did file, at the given line and column
+// (both starting at 1).
+type ParseError struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	if e.File != "" {
+		return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+	}
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+func parseError(file string, pos idl.Position, format string, args ...interface{}) error {
+	return &ParseError{
+		File:    file,
+		Line:    pos.Line,
+		Column:  pos.Column,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
@@ -8,111 +8,243 @@ import (
 	"github.com/aviate-labs/leb128"
 )
 
-
 type typePair struct {
-	Type int64
+	Type         int64
 	single_value int64
-	pair_value [][]big.Int
+	pair_value   [][]big.Int
+	// The argument and return types and the annotations of a func.
+	args        []int64
+	rets        []int64
+	annotations []string
+	// The methods of a service, in the order of the table.
+	methods []methodPair
+}
+
+type methodPair struct {
+	name string
+	tid  int64
+}
+
+// typeTable builds the types of the type definition table. Every entry is
+// built once, and an entry that refers to itself, directly or not, is tied
+// with a knot named after its index.
+type typeTable struct {
+	raw      []typePair
+	labels   Labels
+	types    []Type
+	knots    []*Knot
+	visiting []bool
 }
 
-func _Decode(raw_table []typePair, index int64) (Type, error){
-	pair := raw_table[index]
+func newTypeTable(raw_table []typePair, labels Labels) *typeTable {
+	return &typeTable{
+		raw:      raw_table,
+		labels:   labels,
+		types:    make([]Type, len(raw_table)),
+		knots:    make([]*Knot, len(raw_table)),
+		visiting: make([]bool, len(raw_table)),
+	}
+}
+
+// _DecodeIndex returns the type with the given index, which refers to the
+// table if it is not negative and to a primitive type otherwise.
+func (tt *typeTable) _DecodeIndex(tid int64) (Type, error) {
+	if tid < 0 {
+		return getType(tid)
+	}
+	if int(tid) >= len(tt.raw) {
+		return nil, &FormatError{
+			Description: fmt.Sprintf("type index out of range: %d", tid),
+		}
+	}
+	if tt.types[tid] != nil {
+		if tt.knots[tid] != nil {
+			return tt.knots[tid], nil
+		}
+		return tt.types[tid], nil
+	}
+	if tt.visiting[tid] {
+		if tt.knots[tid] == nil {
+			tt.knots[tid] = NewKnot(fmt.Sprintf("table%d", tid))
+		}
+		return tt.knots[tid], nil
+	}
+	tt.visiting[tid] = true
+	t, err := tt._Decode(tt.raw[tid])
+	tt.visiting[tid] = false
+	if err != nil {
+		return nil, err
+	}
+	if tt.knots[tid] != nil {
+		tt.knots[tid].Fill(t)
+	}
+	tt.types[tid] = t
+	return t, nil
+}
+
+func (tt *typeTable) _Decode(pair typePair) (Type, error) {
 	switch pair.Type {
 	case optType:
-		tid := pair.single_value
-		var v Type
-		var err error
-		if tid >= 0 {
-			if int(tid) >= len(raw_table) {
-				return nil, nil
-			}
-			v, err = _Decode(raw_table, tid)
-		} else{
-			v, err = getType(tid)
-		}
+		v, err := tt._DecodeIndex(pair.single_value)
 		if err != nil {
 			return nil, err
 		}
 		return &Opt{v}, nil
 	case vecType:
-		tid := pair.single_value
-		var v Type
-		var err error
-		if tid >= 0 {
-			if int(tid) >= len(raw_table) {
-				return nil, nil
-			}
-			v, err = _Decode(raw_table, tid)
-		} else{
-			v, err = getType(tid)
-		}
+		v, err := tt._DecodeIndex(pair.single_value)
 		if err != nil {
 			return nil, err
 		}
 		return &Vec{v}, nil
-		//tds = append(tds, &Vec{v})
 	case recType:
-		l := len(pair.pair_value)
 		var fields []Field
-		for i := 0; i < int(l); i++ {
-			h := pair.pair_value[i][0]
-			tid := pair.pair_value[i][1]
-			var v Type
-			var err error
-			if tid.Int64() >= 0 {
-				if int(tid.Int64()) >= len(raw_table) {
-					return nil, nil
-				}
-				v, err = _Decode(raw_table, tid.Int64())
-			} else{
-				v, err = getType(tid.Int64())
-			}
-			
+		for _, f := range pair.pair_value {
+			v, err := tt._DecodeIndex(f[1].Int64())
 			if err != nil {
 				return nil, err
 			}
 			fields = append(fields, Field{
-				Name: h.String(),
+				Name: tt.labels.Name(f[0].String()),
 				Type: v,
 			})
 		}
 		return &Rec{Fields: fields}, nil
 	case varType:
-		l := len(pair.pair_value)
 		var fields []Field
-		for i := 0; i < int(l); i++ {
-			h := pair.pair_value[i][0]
-			tid := pair.pair_value[i][1]
-			var v Type
-			var err error
-			if tid.Int64() >= 0 {
-				if int(tid.Int64()) >= len(raw_table) {
-					return nil, nil
-				}
-				v, err = _Decode(raw_table, tid.Int64())
-			} else{
-				v, err = getType(tid.Int64())
-			}
-			
+		for _, f := range pair.pair_value {
+			v, err := tt._DecodeIndex(f[1].Int64())
 			if err != nil {
 				return nil, err
 			}
 			fields = append(fields, Field{
-				Name: h.String(),
+				Name: tt.labels.Name(f[0].String()),
 				Type: v,
 			})
 		}
 		return &Variant{Fields: fields}, nil
+	case funcType:
+		return tt._DecodeFunc(pair)
+	case serviceType:
+		var methods []Method
+		for _, m := range pair.methods {
+			if m.tid < 0 || int(m.tid) >= len(tt.raw) || tt.raw[m.tid].Type != funcType {
+				return nil, &FormatError{
+					Description: fmt.Sprintf("service method %s is not a func", m.name),
+				}
+			}
+			f, err := tt._DecodeFunc(tt.raw[m.tid])
+			if err != nil {
+				return nil, err
+			}
+			methods = append(methods, Method{
+				Name: m.name,
+				Func: f,
+			})
+		}
+		return &Service{methods: methods}, nil
+	}
+	return nil, &FormatError{
+		Description: fmt.Sprintf("type: not a composite type: %d", pair.Type),
 	}
-	return nil, nil
 }
 
+func (tt *typeTable) _DecodeFunc(pair typePair) (*Func, error) {
+	f := &Func{Annotations: pair.annotations}
+	for _, tid := range pair.args {
+		t, err := tt._DecodeIndex(tid)
+		if err != nil {
+			return nil, err
+		}
+		f.ArgTypes = append(f.ArgTypes, t)
+	}
+	for _, tid := range pair.rets {
+		t, err := tt._DecodeIndex(tid)
+		if err != nil {
+			return nil, err
+		}
+		f.RetTypes = append(f.RetTypes, t)
+	}
+	return f, nil
+}
+
+// decodeTypeIndexes reads a length-prefixed sequence of type indexes.
+func decodeTypeIndexes(r *bytes.Reader) ([]int64, error) {
+	l, err := leb128.DecodeUnsigned(r)
+	if err != nil {
+		return nil, err
+	}
+	var tids []int64
+	for i := 0; i < int(l.Int64()); i++ {
+		t, err := leb128.DecodeSigned(r)
+		if err != nil {
+			return nil, err
+		}
+		tids = append(tids, t.Int64())
+	}
+	return tids, nil
+}
+
+// decodeText reads a length-prefixed UTF-8 string.
+func decodeText(r *bytes.Reader) (string, error) {
+	l, err := leb128.DecodeUnsigned(r)
+	if err != nil {
+		return "", err
+	}
+	if !l.IsInt64() || l.Int64() > int64(r.Len()) {
+		return "", &FormatError{
+			Description: "text: too long",
+		}
+	}
+	bs := make([]byte, l.Int64())
+	if _, err := r.Read(bs); err != nil && len(bs) != 0 {
+		return "", err
+	}
+	return string(bs), nil
+}
+
+// Decode decodes the arguments of a message within the limits of
+// DefaultDecoderConfig.
 func Decode(bs []byte) ([]Type, []interface{}, error) {
+	return DefaultDecoderConfig.Decode(bs)
+}
+
+// DecodeLabeled decodes the arguments like Decode, naming the record and
+// variant fields after the labels instead of their ids.
+func DecodeLabeled(bs []byte, labels Labels) ([]Type, []interface{}, error) {
+	return DefaultDecoderConfig.DecodeLabeled(bs, labels)
+}
+
+func decode(bs []byte, labels Labels, config DecoderConfig) ([]Type, []interface{}, error) {
+	ts, r, err := decodeTypes(bs, labels, config)
+	if err != nil {
+		return nil, nil, err
+	}
+	var vs []interface{}
+	for _, t := range ts {
+		v, err := t.Decode(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		vs = append(vs, v)
+	}
+	if r.Len() != 0 {
+		return nil, nil, fmt.Errorf("too long")
+	}
+	return ts, vs, nil
+}
+
+// decodeTypes decodes the header of a message, returning the types of the
+// arguments and a reader of their values, which are checked to be within
+// the limits.
+func decodeTypes(bs []byte, labels Labels, config DecoderConfig) ([]Type, *bytes.Reader, error) {
 	if len(bs) == 0 {
 		return nil, nil, &FormatError{
 			Description: "empty",
 		}
 	}
+	if config.MaxSize != 0 && len(bs) > config.MaxSize {
+		return nil, nil, &LimitError{Limit: LimitSize, Max: config.MaxSize}
+	}
 
 	r := bytes.NewReader(bs)
 
@@ -140,6 +272,9 @@ func Decode(bs []byte) ([]Type, []interface{}, error) {
 		if err != nil {
 			return nil, nil, err
 		}
+		if config.MaxTypeTableEntries != 0 && (!tdtl.IsInt64() || tdtl.Int64() > int64(config.MaxTypeTableEntries)) {
+			return nil, nil, &LimitError{Limit: LimitTypeTable, Max: config.MaxTypeTableEntries}
+		}
 		for i := 0; i < int(tdtl.Int64()); i++ {
 			tid, err := leb128.DecodeSigned(r)
 			if err != nil {
@@ -152,9 +287,9 @@ func Decode(bs []byte) ([]Type, []interface{}, error) {
 					return nil, nil, err
 				}
 				raw_table = append(raw_table, typePair{
-					Type: tid.Int64(),
+					Type:         tid.Int64(),
 					single_value: t.Int64(),
-					pair_value: nil,
+					pair_value:   nil,
 				})
 			case vecType:
 				t, err := leb128.DecodeSigned(r)
@@ -162,9 +297,9 @@ func Decode(bs []byte) ([]Type, []interface{}, error) {
 					return nil, nil, err
 				}
 				raw_table = append(raw_table, typePair{
-					Type: tid.Int64(),
+					Type:         tid.Int64(),
 					single_value: t.Int64(),
-					pair_value: nil,
+					pair_value:   nil,
 				})
 			case recType:
 				l, err := leb128.DecodeUnsigned(r)
@@ -181,14 +316,19 @@ func Decode(bs []byte) ([]Type, []interface{}, error) {
 					if err != nil {
 						return nil, nil, err
 					}
+					if len(fields) != 0 && h.Cmp(&fields[len(fields)-1][0]) <= 0 {
+						return nil, nil, &FormatError{
+							Description: fmt.Sprintf("field ids are not strictly increasing: %s", h),
+						}
+					}
 					fields = append(fields, []big.Int{*h, *t})
 				}
 				raw_table = append(raw_table, typePair{
-					Type: tid.Int64(),
+					Type:         tid.Int64(),
 					single_value: 99,
-					pair_value: fields,
-				})	
-			case varType:	
+					pair_value:   fields,
+				})
+			case varType:
 				l, err := leb128.DecodeUnsigned(r)
 				if err != nil {
 					return nil, nil, err
@@ -203,30 +343,96 @@ func Decode(bs []byte) ([]Type, []interface{}, error) {
 					if err != nil {
 						return nil, nil, err
 					}
+					if len(fields) != 0 && h.Cmp(&fields[len(fields)-1][0]) <= 0 {
+						return nil, nil, &FormatError{
+							Description: fmt.Sprintf("field ids are not strictly increasing: %s", h),
+						}
+					}
 					fields = append(fields, []big.Int{*h, *t})
 				}
 				raw_table = append(raw_table, typePair{
-					Type: tid.Int64(),
+					Type:         tid.Int64(),
 					single_value: 99,
-					pair_value: fields,
-				})	
+					pair_value:   fields,
+				})
 			case funcType:
-				//TODO
-				return nil, nil, nil
+				args, err := decodeTypeIndexes(r)
+				if err != nil {
+					return nil, nil, err
+				}
+				rets, err := decodeTypeIndexes(r)
+				if err != nil {
+					return nil, nil, err
+				}
+				l, err := leb128.DecodeUnsigned(r)
+				if err != nil {
+					return nil, nil, err
+				}
+				var annotations []string
+				for i := 0; i < int(l.Int64()); i++ {
+					a, err := r.ReadByte()
+					if err != nil {
+						return nil, nil, err
+					}
+					annotation, ok := funcAnnotations[a]
+					if !ok {
+						return nil, nil, &FormatError{
+							Description: fmt.Sprintf("invalid function annotation: %d", a),
+						}
+					}
+					annotations = append(annotations, annotation)
+				}
+				raw_table = append(raw_table, typePair{
+					Type:        tid.Int64(),
+					args:        args,
+					rets:        rets,
+					annotations: annotations,
+				})
 			case serviceType:
-				//TODO
-				return nil, nil, nil
+				l, err := leb128.DecodeUnsigned(r)
+				if err != nil {
+					return nil, nil, err
+				}
+				var methods []methodPair
+				for i := 0; i < int(l.Int64()); i++ {
+					name, err := decodeText(r)
+					if err != nil {
+						return nil, nil, err
+					}
+					t, err := leb128.DecodeSigned(r)
+					if err != nil {
+						return nil, nil, err
+					}
+					if len(methods) != 0 && name <= methods[len(methods)-1].name {
+						return nil, nil, &FormatError{
+							Description: fmt.Sprintf("method names are not strictly increasing: %s", name),
+						}
+					}
+					methods = append(methods, methodPair{
+						name: name,
+						tid:  t.Int64(),
+					})
+				}
+				raw_table = append(raw_table, typePair{
+					Type:    tid.Int64(),
+					methods: methods,
+				})
+			default:
+				return nil, nil, &FormatError{
+					Description: fmt.Sprintf("type: invalid type table entry: %d", tid.Int64()),
+				}
 			}
 		}
 	}
 	var tds []Type
 	{ // T
-		for i, _ := range(raw_table){
-			t, err := _Decode(raw_table, int64(i))
-			if err != nil {
+		tt := newTypeTable(raw_table, labels)
+		for i := range raw_table {
+			if _, err := tt._DecodeIndex(int64(i)); err != nil {
 				return nil, nil, err
 			}
-			tds = append(tds, t)
+			// The definition rather than the knot of the entry.
+			tds = append(tds, tt.types[i])
 		}
 	}
 
@@ -249,25 +455,27 @@ func Decode(bs []byte) ([]Type, []interface{}, error) {
 					return nil, nil, err
 				}
 			} else {
+				if int(tid.Int64()) >= len(tds) {
+					return nil, nil, &FormatError{
+						Description: fmt.Sprintf("type index out of range: %d", tid.Int64()),
+					}
+				}
 				t = tds[int(tid.Int64())]
 			}
 			ts = append(ts, t)
 		}
 	}
 
-	var vs []interface{}
 	{ // M
-		for i := 0; i < int(tsl.Int64()); i++ {
-			v, err := ts[i].Decode(r)
-			if err != nil {
+		// Check the limits on a copy of the reader, so that no value is
+		// decoded if they are exceeded.
+		l := &limiter{config: config}
+		values := bytes.NewReader(bs[len(bs)-r.Len():])
+		for _, t := range ts {
+			if err := l.check(t, values, 1); err != nil {
 				return nil, nil, err
 			}
-			vs = append(vs, v)
 		}
 	}
-
-	if r.Len() != 0 {
-		return nil, nil, fmt.Errorf("too long")
-	}
-	return ts, vs, nil
+	return ts, r, nil
 }
@@ -0,0 +1,85 @@
+package idl_test
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/icpfans-xyz/agent-go/candid/idl"
+	"github.com/mix-labs/IC-Go/utils/principal"
+)
+
+func ExampleDecode_service() {
+	bs, _ := hex.DecodeString("4449444c026a0171017d00690103666f6f0001010103caffee")
+	ts, vs, err := idl.Decode(bs)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(ts)
+	fmt.Println(vs[0].(principal.Principal).Encode())
+	for _, m := range ts[0].(*idl.Service).Methods() {
+		fmt.Println(m.Name, m.Func)
+	}
+	// Output:
+	// [service {foo:(text) -> (nat)}]
+	// w7x7r-cok77-xa
+	// foo (text) -> (nat)
+}
+
+func ExampleDecode_func() {
+	p, _ := principal.Decode("w7x7r-cok77-xa")
+	callback := idl.NewFunc(
+		[]idl.Type{idl.NewRec(map[string]idl.Type{"start": new(idl.Nat)})},
+		[]idl.Type{idl.NewVec(new(idl.Nat))},
+		[]string{"query"},
+	)
+	bs, err := idl.Encode(
+		[]idl.Type{idl.NewRec(map[string]idl.Type{"callback": callback})},
+		[]interface{}{map[string]interface{}{
+			"callback": idl.PrincipalMethod{Principal: p, Method: "get_blocks"},
+		}},
+	)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	ts, vs, err := idl.Decode(bs)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(ts)
+	for _, v := range vs[0].(map[string]interface{}) {
+		pm := v.(idl.PrincipalMethod)
+		fmt.Println(pm.Principal.Encode(), pm.Method)
+	}
+	// Output:
+	// [record {2131139013:(record {2215343202:nat}) -> (vec nat) query}]
+	// w7x7r-cok77-xa get_blocks
+}
+
+func ExampleDecode_invalid() {
+	for _, s := range []string{
+		// A func with an unknown annotation.
+		"4449444c016a0000010400",
+		// An out of range type index.
+		"4449444c016e050000",
+		// A service with its methods out of order, then twice the same method.
+		"4449444c026a000000690201620001610001010100",
+		"4449444c026a000000690201610001610001010100",
+		// A float32 and a float64 NaN.
+		"4449444c0001730000c07f",
+		"4449444c000172000000000000f87f",
+	} {
+		bs, _ := hex.DecodeString(s)
+		_, _, err := idl.Decode(bs)
+		fmt.Println(err)
+	}
+	// Output:
+	// () invalid function annotation: 4
+	// () type index out of range: 5
+	// () method names are not strictly increasing: a
+	// () method names are not strictly increasing: a
+	// () float: NaN is not supported
+	// () float: NaN is not supported
+}
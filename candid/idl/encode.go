@@ -11,16 +11,26 @@ func Encode(argumentTypes []Type, arguments []interface{}) ([]byte, error) {
 	if len(arguments) < len(argumentTypes) {
 		return nil, fmt.Errorf("invalid number of arguments")
 	}
+	return encode(argumentTypes, func(i int) ([]byte, error) {
+		return argumentTypes[i].EncodeValue(arguments[i])
+	})
+}
+
+// encode encodes a message with arguments of the given types, whose values
+// are encoded by encodeValue.
+func encode(argumentTypes []Type, encodeValue func(i int) ([]byte, error)) ([]byte, error) {
 
 	// T
 	tdt := &TypeDefinitionTable{
 		Indexes: make(map[string]int),
 	}
 	for _, t := range argumentTypes {
-		t.AddTypeDefinition(tdt)
+		if err := t.AddTypeDefinition(tdt); err != nil {
+			return nil, err
+		}
 	}
 
-	tdtl, err := leb128.EncodeSigned(big.NewInt(int64(len(tdt.Indexes))))
+	tdtl, err := leb128.EncodeSigned(big.NewInt(int64(len(tdt.Types))))
 	if err != nil {
 		return nil, err
 	}
@@ -46,7 +56,7 @@ func Encode(argumentTypes []Type, arguments []interface{}) ([]byte, error) {
 			ts = append(ts, t...)
 		}
 		{ // M
-			v, err := t.EncodeValue(arguments[i])
+			v, err := encodeValue(i)
 			if err != nil {
 				return nil, err
 			}
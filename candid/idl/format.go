@@ -0,0 +1,272 @@
+package idl
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/mix-labs/IC-Go/utils/principal"
+)
+
+// FormatArgs prints the arguments in the textual format of Candid, like
+// `(record { amount = 10; name = "x" }, opt true)`.
+func FormatArgs(types []Type, values []interface{}) (string, error) {
+	if len(types) != len(values) {
+		return "", fmt.Errorf("expected %d values, got %d", len(types), len(values))
+	}
+	var s []string
+	for i, t := range types {
+		v, err := FormatValue(t, values[i])
+		if err != nil {
+			return "", err
+		}
+		s = append(s, v)
+	}
+	return fmt.Sprintf("(%s)", strings.Join(s, ", ")), nil
+}
+
+// FormatValue prints a value of the type in the textual format of Candid.
+// Numbers of fixed size are annotated with their type, vec nat8 is printed
+// as a blob and records with fields 0, 1, ... as tuples.
+func FormatValue(t Type, v interface{}) (string, error) {
+	switch t := resolve(t).(type) {
+	case *Null, *Reserved:
+		return "null", nil
+	case *Bool:
+		b, ok := v.(bool)
+		if !ok {
+			return "", invalidValue(t, v)
+		}
+		return fmt.Sprintf("%t", b), nil
+	case *Nat, *Int:
+		n, ok := v.(*big.Int)
+		if !ok {
+			return "", invalidValue(t, v)
+		}
+		if base(t) == 0 {
+			return n.String(), nil
+		}
+		return fmt.Sprintf("%s : %s", n, t), nil
+	case *Float:
+		f, ok := v.(*big.Float)
+		if !ok {
+			return "", invalidValue(t, v)
+		}
+		s := f.Text('g', -1)
+		if !strings.ContainsAny(s, ".eIN") {
+			s += ".0"
+		}
+		return fmt.Sprintf("%s : %s", s, t), nil
+	case *Text:
+		s, ok := v.(string)
+		if !ok {
+			return "", invalidValue(t, v)
+		}
+		return quoteText(s), nil
+	case *Principal:
+		p, ok := v.(principal.Principal)
+		if !ok {
+			return "", invalidValue(t, v)
+		}
+		return fmt.Sprintf("principal %q", p.Encode()), nil
+	case *Service:
+		p, ok := v.(principal.Principal)
+		if !ok {
+			return "", invalidValue(t, v)
+		}
+		return fmt.Sprintf("service %q", p.Encode()), nil
+	case *Func:
+		pm, ok := v.(PrincipalMethod)
+		if !ok {
+			return "", invalidValue(t, v)
+		}
+		return fmt.Sprintf("func %q.%s", pm.Principal.Encode(), formatLabel(pm.Method)), nil
+	case *Opt:
+		if v == nil {
+			return "null", nil
+		}
+		s, err := FormatValue(t.Type, v)
+		if err != nil {
+			return "", err
+		}
+		return "opt " + s, nil
+	case *Vec:
+		vs, ok := v.([]interface{})
+		if !ok && v != nil {
+			return "", invalidValue(t, v)
+		}
+		if n, ok := resolve(t.Type).(*Nat); ok && n.Base == 8 {
+			return formatBlob(vs)
+		}
+		var s []string
+		for _, v := range vs {
+			e, err := FormatValue(t.Type, v)
+			if err != nil {
+				return "", err
+			}
+			s = append(s, e)
+		}
+		return formatFields("vec", s), nil
+	case *Rec:
+		fields, ok := v.(map[string]interface{})
+		if !ok && v != nil {
+			return "", invalidValue(t, v)
+		}
+		tuple := isTuple(t)
+		var s []string
+		for _, f := range t.Fields {
+			e, err := FormatValue(f.Type, recordField(fields, f.Name))
+			if err != nil {
+				return "", err
+			}
+			if !tuple {
+				e = fmt.Sprintf("%s = %s", formatLabel(f.Name), e)
+			}
+			s = append(s, e)
+		}
+		return formatFields("record", s), nil
+	case *Variant:
+		var fv FieldValue
+		switch v := v.(type) {
+		case FieldValue:
+			fv = v
+		case *FieldValue:
+			fv = *v
+		default:
+			return "", invalidValue(t, v)
+		}
+		for _, f := range t.Fields {
+			if !sameLabel(f.Name, fv.Name) {
+				continue
+			}
+			if _, ok := resolve(f.Type).(*Null); ok {
+				return fmt.Sprintf("variant { %s }", formatLabel(f.Name)), nil
+			}
+			e, err := FormatValue(f.Type, fv.Value)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("variant { %s = %s }", formatLabel(f.Name), e), nil
+		}
+		return "", fmt.Errorf("unknown variant field %s of %s", fv.Name, t)
+	}
+	return "", fmt.Errorf("cannot format values of type %s", t)
+}
+
+// recordField returns the value of a field in a record, which is keyed by
+// field name or, as returned by Decode, by id.
+func recordField(rec map[string]interface{}, name string) interface{} {
+	if v, ok := rec[name]; ok {
+		return v
+	}
+	return rec[LabelId(name).String()]
+}
+
+// sameLabel reports whether the labels have the same id, like a field name
+// and the id of a decoded field.
+func sameLabel(a, b string) bool {
+	return LabelId(a).Cmp(LabelId(b)) == 0
+}
+
+func invalidValue(t Type, v interface{}) error {
+	return fmt.Errorf("invalid value of type %s: %v", t, v)
+}
+
+// base returns the size in bits of a number type, 0 for nat and int.
+func base(t Type) uint8 {
+	switch t := t.(type) {
+	case *Nat:
+		return t.Base
+	case *Int:
+		return t.Base
+	case *Float:
+		return t.Base
+	}
+	return 0
+}
+
+// isTuple reports whether the fields of the record are 0, 1, ...
+func isTuple(r *Rec) bool {
+	for i, f := range r.Fields {
+		if f.Name != fmt.Sprint(i) {
+			return false
+		}
+	}
+	return len(r.Fields) != 0
+}
+
+func formatFields(keyword string, fields []string) string {
+	if len(fields) == 0 {
+		return keyword + " {}"
+	}
+	return fmt.Sprintf("%s { %s }", keyword, strings.Join(fields, "; "))
+}
+
+var identifierRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// keywords cannot be used as field names without quotes.
+var keywords = map[string]bool{
+	"blob": true, "bool": true, "false": true, "func": true, "import": true,
+	"null": true, "opt": true, "principal": true, "query": true, "record": true,
+	"service": true, "true": true, "type": true, "variant": true, "vec": true,
+}
+
+// formatLabel prints a field name, quoting it if it is not an identifier or
+// a number.
+func formatLabel(name string) string {
+	if identifierRegexp.MatchString(name) && !keywords[name] {
+		return name
+	}
+	if _, ok := new(big.Int).SetString(name, 10); ok && name[0] != '+' && name[0] != '-' {
+		return name
+	}
+	return quoteText(name)
+}
+
+func quoteText(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if unicode.IsPrint(r) {
+				b.WriteRune(r)
+			} else {
+				fmt.Fprintf(&b, `\u{%x}`, r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func formatBlob(vs []interface{}) (string, error) {
+	var b strings.Builder
+	b.WriteString(`blob "`)
+	for _, v := range vs {
+		n, ok := v.(*big.Int)
+		if !ok || !n.IsUint64() || n.Uint64() > 0xff {
+			return "", fmt.Errorf("invalid value of type nat8: %v", v)
+		}
+		c := byte(n.Uint64())
+		if c >= 0x20 && c < 0x7f && c != '"' && c != '\\' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, `\%02x`, c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String(), nil
+}
@@ -0,0 +1,64 @@
+package idl_test
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/icpfans-xyz/agent-go/candid/idl"
+	"github.com/mix-labs/IC-Go/utils/principal"
+)
+
+func ExampleFormatArgs() {
+	types := []idl.Type{
+		idl.NewRec(map[string]idl.Type{
+			"name":   new(idl.Text),
+			"amount": idl.Nat64(),
+		}),
+		idl.NewVariant(map[string]idl.Type{
+			"Ok":  idl.NewVec(idl.Nat8()),
+			"Err": new(idl.Null),
+		}),
+		idl.NewOpt(new(idl.Principal)),
+	}
+	pid, _ := principal.Decode("aaaaa-aa")
+	s, err := idl.FormatArgs(types, []interface{}{
+		map[string]interface{}{
+			"name":   "x\n",
+			"amount": big.NewInt(10),
+		},
+		idl.FieldValue{
+			Name:  "Ok",
+			Value: []interface{}{big.NewInt(0xde), big.NewInt(0xad), big.NewInt('a')},
+		},
+		pid,
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(s)
+	// Output:
+	// (record { name = "x\n"; amount = 10 : nat64 }, variant { Ok = blob "\de\ada" }, opt principal "aaaaa-aa")
+}
+
+func ExampleFormatValue() {
+	tuple := idl.NewRec(map[string]idl.Type{
+		"0": new(idl.Int),
+		"1": idl.Float64(),
+	})
+	s, _ := idl.FormatValue(tuple, map[string]interface{}{
+		"0": big.NewInt(-1),
+		"1": big.NewFloat(2),
+	})
+	fmt.Println(s)
+	s, _ = idl.FormatValue(idl.NewVec(new(idl.Bool)), []interface{}{true, false})
+	fmt.Println(s)
+	s, _ = idl.FormatValue(idl.NewRec(map[string]idl.Type{
+		"type": new(idl.Null),
+	}), map[string]interface{}{"type": nil})
+	fmt.Println(s)
+	// Output:
+	// record { -1; 2.0 : float64 }
+	// vec { true; false }
+	// record { "type" = null }
+}
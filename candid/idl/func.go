@@ -11,6 +11,18 @@ import (
 	"github.com/mix-labs/IC-Go/utils/principal"
 )
 
+var funcAnnotations = map[byte]string{
+	0x01: "query",
+	0x02: "oneway",
+	0x03: "composite_query",
+}
+
+var funcAnnotationBytes = map[string]byte{
+	"query":           0x01,
+	"oneway":          0x02,
+	"composite_query": 0x03,
+}
+
 func encodeTypes(ts []Type, tdt *TypeDefinitionTable) ([]byte, error) {
 	l, err := leb128.EncodeUnsigned(big.NewInt(int64(len(ts))))
 	if err != nil {
@@ -67,14 +79,11 @@ func (f Func) AddTypeDefinition(tdt *TypeDefinitionTable) error {
 	}
 	var vs []byte
 	for _, t := range f.Annotations {
-		switch t {
-		case "query":
-			vs = []byte{0x01}
-		case "oneway":
-			vs = []byte{0x02}
-		default:
+		a, ok := funcAnnotationBytes[t]
+		if !ok {
 			return fmt.Errorf("invalid function annotation: %s", t)
 		}
+		vs = append(vs, a)
 	}
 
 	tdt.Add(f, concat(id, vsa, vsr, l, vs))
@@ -96,33 +105,20 @@ func (f Func) Decode(r *bytes.Reader) (interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
+	if !l.IsInt64() || l.Int64() > int64(r.Len()) {
+		return nil, fmt.Errorf("invalid principal id length: %s", l)
+	}
 	pid := make(principal.Principal, l.Int64())
-	{
-		n, err := r.Read(pid)
-		if err != nil {
-			return nil, err
-		}
-		if n != int(l.Int64()) {
-			return nil, fmt.Errorf("invalid principal id: %d", pid)
-		}
+	if _, err := r.Read(pid); err != nil && len(pid) != 0 {
+		return nil, err
 	}
-	ml, err := leb128.DecodeUnsigned(r)
+	m, err := decodeText(r)
 	if err != nil {
 		return nil, err
 	}
-	m := make([]byte, ml.Int64())
-	{
-		n, err := r.Read(pid)
-		if err != nil {
-			return nil, err
-		}
-		if n != int(l.Int64()) {
-			return nil, fmt.Errorf("invalid method: %d", pid)
-		}
-	}
-	return &PrincipalMethod{
+	return PrincipalMethod{
 		Principal: pid,
-		Method:    string(m),
+		Method:    m,
 	}, nil
 }
 
@@ -15,3 +15,13 @@ func Hash(s string) *big.Int {
 	}
 	return h
 }
+
+// LabelId returns the id of a record or variant field: the number itself for
+// a numeric label, such as the fields "0", "1", ... of tuples, and the hash
+// of the name otherwise.
+func LabelId(name string) *big.Int {
+	if id, ok := new(big.Int).SetString(name, 10); ok && name[0] != '+' && name[0] != '-' && id.BitLen() <= 32 {
+		return id
+	}
+	return Hash(name)
+}
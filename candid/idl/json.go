@@ -0,0 +1,461 @@
+package idl
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/mix-labs/IC-Go/utils/principal"
+)
+
+// maxSafeInteger is the largest integer that JSON parsers reading numbers as
+// float64 represent exactly.
+var maxSafeInteger = big.NewInt(1<<53 - 1)
+
+// JSONConfig converts Candid values to and from JSON, given their types:
+//
+//   - nat and int are numbers, or strings if they are too big for float64.
+//   - blobs (vec nat8) are hex strings, or base64 if Base64Blobs is set.
+//   - records are objects keyed by field names, variants objects with a
+//     single key.
+//   - opt is null or the value.
+//   - principals and services are text, funcs {"principal": ..., "method": ...}.
+//
+// Converting a value to JSON and back gives the same value. Options of types
+// whose values can be null, like opt opt nat, opt null and opt reserved, are
+// rejected, since null could not tell the option of null from null.
+type JSONConfig struct {
+	Base64Blobs bool
+}
+
+// MarshalArgs converts the arguments to a JSON array.
+func (c JSONConfig) MarshalArgs(types []Type, values []interface{}) ([]byte, error) {
+	if len(types) != len(values) {
+		return nil, fmt.Errorf("expected %d values, got %d", len(types), len(values))
+	}
+	vs := make([]interface{}, len(types))
+	for i, t := range types {
+		v, err := c.toJSON(t, values[i])
+		if err != nil {
+			return nil, err
+		}
+		vs[i] = v
+	}
+	return json.Marshal(vs)
+}
+
+// Marshal converts a value of the type, like one returned by Decode, to JSON.
+func (c JSONConfig) Marshal(t Type, v interface{}) ([]byte, error) {
+	j, err := c.toJSON(t, v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalArgs converts a JSON array to arguments that can be encoded.
+// Missing arguments of optional types are null.
+func (c JSONConfig) UnmarshalArgs(types []Type, data []byte) ([]interface{}, error) {
+	j, err := parseJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	vs, ok := j.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a JSON array of arguments, got %s", jsonKind(j))
+	}
+	if len(vs) > len(types) {
+		return nil, fmt.Errorf("expected %d arguments, got %d", len(types), len(vs))
+	}
+	values := make([]interface{}, len(types))
+	for i, t := range types {
+		if i >= len(vs) {
+			if !isOptional(t) {
+				return nil, fmt.Errorf("missing argument of type %s", t)
+			}
+			continue
+		}
+		v, err := c.fromJSON(t, vs[i])
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// Unmarshal converts JSON to a value of the type that can be encoded.
+func (c JSONConfig) Unmarshal(t Type, data []byte) (interface{}, error) {
+	j, err := parseJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	return c.fromJSON(t, j)
+}
+
+func parseJSON(data []byte) (interface{}, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var j interface{}
+	if err := d.Decode(&j); err != nil {
+		return nil, err
+	}
+	if d.More() {
+		return nil, fmt.Errorf("unexpected data after JSON value")
+	}
+	return j, nil
+}
+
+func isBlob(t *Vec) bool {
+	n, ok := resolve(t.Type).(*Nat)
+	return ok && n.Base == 8
+}
+
+// nullable reports whether null is the JSON of a value of the type.
+func nullable(t Type) bool {
+	switch resolve(t).(type) {
+	case *Null, *Reserved, *Opt:
+		return true
+	}
+	return false
+}
+
+func ambiguousOpt(t *Opt) error {
+	return fmt.Errorf("%s has no JSON representation: null is both an option and its value", t)
+}
+
+func (c JSONConfig) toJSON(t Type, v interface{}) (interface{}, error) {
+	switch t := resolve(t).(type) {
+	case *Null, *Reserved:
+		return nil, nil
+	case *Bool:
+		if _, ok := v.(bool); !ok {
+			return nil, invalidValue(t, v)
+		}
+		return v, nil
+	case *Text:
+		if _, ok := v.(string); !ok {
+			return nil, invalidValue(t, v)
+		}
+		return v, nil
+	case *Nat, *Int:
+		n, ok := v.(*big.Int)
+		if !ok {
+			return nil, invalidValue(t, v)
+		}
+		if new(big.Int).Abs(n).Cmp(maxSafeInteger) > 0 {
+			return n.String(), nil
+		}
+		return json.Number(n.String()), nil
+	case *Float:
+		f, ok := v.(*big.Float)
+		if !ok {
+			return nil, invalidValue(t, v)
+		}
+		if f.IsInf() {
+			if f.Sign() < 0 {
+				return "-Infinity", nil
+			}
+			return "Infinity", nil
+		}
+		x, _ := f.Float64()
+		return x, nil
+	case *Principal, *Service:
+		p, ok := v.(principal.Principal)
+		if !ok {
+			return nil, invalidValue(t, v)
+		}
+		return p.Encode(), nil
+	case *Func:
+		pm, ok := v.(PrincipalMethod)
+		if !ok {
+			return nil, invalidValue(t, v)
+		}
+		return map[string]interface{}{
+			"principal": pm.Principal.Encode(),
+			"method":    pm.Method,
+		}, nil
+	case *Opt:
+		if nullable(t.Type) {
+			return nil, ambiguousOpt(t)
+		}
+		if v == nil {
+			return nil, nil
+		}
+		return c.toJSON(t.Type, v)
+	case *Vec:
+		vs, ok := v.([]interface{})
+		if !ok && v != nil {
+			return nil, invalidValue(t, v)
+		}
+		if isBlob(t) {
+			bs := make([]byte, len(vs))
+			for i, v := range vs {
+				n, ok := v.(*big.Int)
+				if !ok || !n.IsUint64() || n.Uint64() > 0xff {
+					return nil, fmt.Errorf("invalid value of type nat8: %v", v)
+				}
+				bs[i] = byte(n.Uint64())
+			}
+			if c.Base64Blobs {
+				return base64.StdEncoding.EncodeToString(bs), nil
+			}
+			return hex.EncodeToString(bs), nil
+		}
+		js := make([]interface{}, len(vs))
+		for i, v := range vs {
+			e, err := c.toJSON(t.Type, v)
+			if err != nil {
+				return nil, err
+			}
+			js[i] = e
+		}
+		return js, nil
+	case *Rec:
+		rec, ok := v.(map[string]interface{})
+		if !ok && v != nil {
+			return nil, invalidValue(t, v)
+		}
+		obj := make(map[string]interface{})
+		for _, f := range t.Fields {
+			e, err := c.toJSON(f.Type, recordField(rec, f.Name))
+			if err != nil {
+				return nil, err
+			}
+			obj[f.Name] = e
+		}
+		return obj, nil
+	case *Variant:
+		var fv FieldValue
+		switch v := v.(type) {
+		case FieldValue:
+			fv = v
+		case *FieldValue:
+			fv = *v
+		default:
+			return nil, invalidValue(t, v)
+		}
+		for _, f := range t.Fields {
+			if !sameLabel(fv.Name, f.Name) {
+				continue
+			}
+			e, err := c.toJSON(f.Type, fv.Value)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{f.Name: e}, nil
+		}
+		return nil, fmt.Errorf("unknown variant field %s of %s", fv.Name, t)
+	}
+	return nil, fmt.Errorf("cannot convert values of type %s to JSON", t)
+}
+
+// jsonKind describes a JSON value for errors.
+func jsonKind(j interface{}) string {
+	switch j.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "a boolean"
+	case json.Number:
+		return "a number"
+	case string:
+		return "a string"
+	case []interface{}:
+		return "an array"
+	}
+	return "an object"
+}
+
+func invalidJSON(t Type, j interface{}) error {
+	return fmt.Errorf("expected JSON for %s, got %s", t, jsonKind(j))
+}
+
+func (c JSONConfig) fromJSON(t Type, j interface{}) (interface{}, error) {
+	switch t := resolve(t).(type) {
+	case *Null:
+		if j != nil {
+			return nil, invalidJSON(t, j)
+		}
+		return nil, nil
+	case *Reserved:
+		return nil, nil
+	case *Bool:
+		if _, ok := j.(bool); !ok {
+			return nil, invalidJSON(t, j)
+		}
+		return j, nil
+	case *Text:
+		if _, ok := j.(string); !ok {
+			return nil, invalidJSON(t, j)
+		}
+		return j, nil
+	case *Nat, *Int:
+		var s string
+		switch j := j.(type) {
+		case json.Number:
+			s = j.String()
+		case string:
+			s = j
+		default:
+			return nil, invalidJSON(t, j)
+		}
+		n, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid %s: %s", t, s)
+		}
+		if err := checkRange(t, n); err != nil {
+			return nil, err
+		}
+		return n, nil
+	case *Float:
+		switch j := j.(type) {
+		case json.Number:
+			x, err := j.Float64()
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %s", t, j)
+			}
+			return big.NewFloat(x), nil
+		case string:
+			switch j {
+			case "Infinity":
+				return new(big.Float).SetInf(false), nil
+			case "-Infinity":
+				return new(big.Float).SetInf(true), nil
+			}
+		}
+		return nil, invalidJSON(t, j)
+	case *Principal, *Service:
+		s, ok := j.(string)
+		if !ok {
+			return nil, invalidJSON(t, j)
+		}
+		p, err := principal.Decode(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid principal %q: %v", s, err)
+		}
+		return p, nil
+	case *Func:
+		obj, ok := j.(map[string]interface{})
+		if !ok {
+			return nil, invalidJSON(t, j)
+		}
+		p, err := c.fromJSON(new(Principal), obj["principal"])
+		if err != nil {
+			return nil, err
+		}
+		method, ok := obj["method"].(string)
+		if !ok {
+			return nil, invalidJSON(new(Text), obj["method"])
+		}
+		return PrincipalMethod{Principal: p.(principal.Principal), Method: method}, nil
+	case *Opt:
+		if nullable(t.Type) {
+			return nil, ambiguousOpt(t)
+		}
+		if j == nil {
+			return nil, nil
+		}
+		return c.fromJSON(t.Type, j)
+	case *Vec:
+		if isBlob(t) {
+			s, ok := j.(string)
+			if !ok {
+				return nil, invalidJSON(t, j)
+			}
+			var bs []byte
+			var err error
+			if c.Base64Blobs {
+				bs, err = base64.StdEncoding.DecodeString(s)
+			} else {
+				bs, err = hex.DecodeString(s)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid blob: %v", err)
+			}
+			var vs []interface{}
+			for _, b := range bs {
+				vs = append(vs, big.NewInt(int64(b)))
+			}
+			return vs, nil
+		}
+		js, ok := j.([]interface{})
+		if !ok {
+			return nil, invalidJSON(t, j)
+		}
+		var vs []interface{}
+		for _, j := range js {
+			v, err := c.fromJSON(t.Type, j)
+			if err != nil {
+				return nil, err
+			}
+			vs = append(vs, v)
+		}
+		return vs, nil
+	case *Rec:
+		obj, ok := j.(map[string]interface{})
+		if !ok {
+			return nil, invalidJSON(t, j)
+		}
+		rec := make(map[string]interface{})
+		for _, f := range t.Fields {
+			e, ok := obj[f.Name]
+			if !ok && !isOptional(f.Type) {
+				return nil, fmt.Errorf("missing field %s of %s", f.Name, t)
+			}
+			v, err := c.fromJSON(f.Type, e)
+			if err != nil {
+				return nil, err
+			}
+			rec[f.Name] = v
+		}
+		for name := range obj {
+			if _, ok := rec[name]; !ok {
+				return nil, fmt.Errorf("unknown field %s of %s", name, t)
+			}
+		}
+		return rec, nil
+	case *Variant:
+		obj, ok := j.(map[string]interface{})
+		if !ok || len(obj) != 1 {
+			return nil, fmt.Errorf("expected JSON for %s, got %s instead of an object with one key", t, jsonKind(j))
+		}
+		for name, e := range obj {
+			for _, f := range t.Fields {
+				if f.Name != name {
+					continue
+				}
+				v, err := c.fromJSON(f.Type, e)
+				if err != nil {
+					return nil, err
+				}
+				return FieldValue{Name: f.Name, Value: v}, nil
+			}
+			return nil, fmt.Errorf("unknown variant field %s of %s", name, t)
+		}
+	}
+	return nil, fmt.Errorf("cannot convert JSON to values of type %s", t)
+}
+
+// checkRange checks that the number fits the size of its type.
+func checkRange(t Type, n *big.Int) error {
+	bits := base(t)
+	_, signed := t.(*Int)
+	if !signed && n.Sign() < 0 {
+		return fmt.Errorf("%s is out of range for %s", n, t)
+	}
+	if bits == 0 {
+		return nil
+	}
+	if signed {
+		bits--
+	}
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(bits))
+	if n.Cmp(limit) >= 0 || n.Cmp(new(big.Int).Neg(limit)) < 0 {
+		return fmt.Errorf("%s is out of range for %s", n, t)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package idl_test
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/icpfans-xyz/agent-go/candid/idl"
+)
+
+func ExampleJSONConfig_Marshal() {
+	t := idl.NewRec(map[string]idl.Type{
+		"amount": idl.Nat64(),
+		"fee":    new(idl.Nat),
+		"memo":   idl.NewVec(idl.Nat8()),
+		"to":     idl.NewOpt(new(idl.Text)),
+		"result": idl.NewVariant(map[string]idl.Type{
+			"Ok":  new(idl.Null),
+			"Err": new(idl.Text),
+		}),
+	})
+	amount, _ := new(big.Int).SetString("18446744073709551615", 10)
+	bs, _ := idl.Encode([]idl.Type{t}, []interface{}{
+		map[string]interface{}{
+			"amount": amount,
+			"fee":    big.NewInt(10000),
+			"memo":   []interface{}{big.NewInt(0xca), big.NewInt(0xfe)},
+			"to":     nil,
+			"result": idl.FieldValue{Name: "Ok"},
+		},
+	})
+	// The type of the values decoded without labels has ids as field names.
+	ts, vs, _ := idl.Decode(bs)
+	for _, c := range []idl.JSONConfig{{}, {Base64Blobs: true}} {
+		j, err := c.Marshal(ts[0], vs[0])
+		fmt.Println(string(j), err)
+	}
+	j, err := idl.JSONConfig{}.Marshal(t, vs[0])
+	fmt.Println(string(j), err)
+	// Output:
+	// {"1213809850":"cafe","142895325":{"17724":null},"25979":null,"3573748184":"18446744073709551615","5094982":10000} <nil>
+	// {"1213809850":"yv4=","142895325":{"17724":null},"25979":null,"3573748184":"18446744073709551615","5094982":10000} <nil>
+	// {"amount":"18446744073709551615","fee":10000,"memo":"cafe","result":{"Ok":null},"to":null} <nil>
+}
+
+func ExampleJSONConfig_Unmarshal() {
+	t := idl.NewRec(map[string]idl.Type{
+		"amount": idl.Nat64(),
+		"to":     idl.NewOpt(new(idl.Text)),
+		"result": idl.NewVariant(map[string]idl.Type{
+			"Ok":  new(idl.Null),
+			"Err": new(idl.Text),
+		}),
+	})
+	c := idl.JSONConfig{}
+	for _, j := range []string{
+		`{"amount": "12", "to": "bob", "result": {"Err": "oops"}}`,
+		`{"amount": 12, "to": null, "result": {"Ok": null}}`,
+		`{"amount": 12, "result": {"Ok": null}}`,
+		`{"amount": -1, "result": {"Ok": null}}`,
+		`{"amount": 1, "result": {"Ok": null, "Err": "oops"}}`,
+	} {
+		v, err := c.Unmarshal(t, []byte(j))
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		s, _ := idl.FormatValue(t, v)
+		fmt.Println(s)
+		// Converting the value back gives the same JSON.
+		b, _ := c.Marshal(t, v)
+		fmt.Println(string(b))
+	}
+	// Output:
+	// record { to = opt "bob"; result = variant { Err = "oops" }; amount = 12 : nat64 }
+	// {"amount":12,"result":{"Err":"oops"},"to":"bob"}
+	// record { to = null; result = variant { Ok }; amount = 12 : nat64 }
+	// {"amount":12,"result":{"Ok":null},"to":null}
+	// record { to = null; result = variant { Ok }; amount = 12 : nat64 }
+	// {"amount":12,"result":{"Ok":null},"to":null}
+	// -1 is out of range for nat64
+	// expected JSON for variant {Ok:null; Err:text}, got an object instead of an object with one key
+}
+
+func ExampleJSONConfig_UnmarshalArgs() {
+	types := []idl.Type{new(idl.Principal), idl.NewOpt(new(idl.Bool))}
+	vs, err := idl.JSONConfig{}.UnmarshalArgs(types, []byte(`["aaaaa-aa"]`))
+	fmt.Println(vs, err)
+	j, err := idl.JSONConfig{}.MarshalArgs(types, vs)
+	fmt.Println(string(j), err)
+	// Output:
+	// [[] <nil>] <nil>
+	// ["aaaaa-aa",null] <nil>
+}
+
+func ExampleJSONConfig_nullable() {
+	for _, t := range []idl.Type{
+		idl.NewOpt(idl.NewOpt(new(idl.Nat))),
+		idl.NewOpt(new(idl.Null)),
+		idl.NewOpt(new(idl.Reserved)),
+	} {
+		_, err := idl.JSONConfig{}.Marshal(t, nil)
+		fmt.Println(err)
+		_, err = idl.JSONConfig{}.Unmarshal(t, []byte(`null`))
+		fmt.Println(err)
+	}
+	// Output:
+	// opt opt nat has no JSON representation: null is both an option and its value
+	// opt opt nat has no JSON representation: null is both an option and its value
+	// opt null has no JSON representation: null is both an option and its value
+	// opt null has no JSON representation: null is both an option and its value
+	// opt reserved has no JSON representation: null is both an option and its value
+	// opt reserved has no JSON representation: null is both an option and its value
+}
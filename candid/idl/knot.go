@@ -0,0 +1,127 @@
+package idl
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/aviate-labs/leb128"
+)
+
+// Knot is a named reference to a type, which allows recursive types such as
+// `type List = opt record { head : nat; tail : List }`. A knot is created
+// first, so that it can be used within its own definition, and then filled
+// with that definition:
+//
+//	list := NewKnot("List")
+//	list.Fill(NewOpt(NewRec(map[string]Type{"head": new(Nat), "tail": list})))
+//
+// Knots are printed by name, so names must be unique within a message.
+type Knot struct {
+	Name string
+	t    Type
+}
+
+func NewKnot(name string) *Knot {
+	return &Knot{Name: name}
+}
+
+// Fill ties the knot to the type it refers to. A knot cannot refer to
+// itself, directly or through other knots, as such a type has no values.
+func (k *Knot) Fill(t Type) error {
+	for next := t; next != nil; {
+		inner, ok := next.(*Knot)
+		if !ok {
+			break
+		}
+		if inner == k {
+			return fmt.Errorf("knot %s refers to itself", k.Name)
+		}
+		next = inner.t
+	}
+	k.t = t
+	return nil
+}
+
+// Type returns the type the knot refers to, nil if it is not filled yet.
+func (k *Knot) Type() Type {
+	return k.t
+}
+
+func (k *Knot) key() string {
+	return fmt.Sprintf("μ%s", k.Name)
+}
+
+// isComposite reports whether the knot refers to a type that has an entry in
+// the type definition table.
+func (k *Knot) isComposite() bool {
+	switch t := k.t.(type) {
+	case PrimType:
+		return false
+	case *Knot:
+		return t.isComposite()
+	default:
+		return true
+	}
+}
+
+func (k *Knot) AddTypeDefinition(tdt *TypeDefinitionTable) error {
+	if k.t == nil {
+		return fmt.Errorf("unfilled type: %s", k.Name)
+	}
+	if !k.isComposite() {
+		return k.t.AddTypeDefinition(tdt)
+	}
+	if _, ok := tdt.Indexes[k.key()]; ok {
+		return nil
+	}
+	// Reserve an entry before adding the definition, which can refer to it.
+	i := len(tdt.Types)
+	tdt.Indexes[k.key()] = i
+	tdt.Types = append(tdt.Types, nil)
+	if err := k.t.AddTypeDefinition(tdt); err != nil {
+		return err
+	}
+	key := k.t.String()
+	if inner, isKnot := k.t.(*Knot); isKnot {
+		key = inner.key()
+	}
+	j, ok := tdt.Indexes[key]
+	if !ok {
+		return fmt.Errorf("missing type index for: %s", k.t)
+	}
+	tdt.Types[i] = tdt.Types[j]
+	return nil
+}
+
+func (k *Knot) Decode(r *bytes.Reader) (interface{}, error) {
+	if k.t == nil {
+		return nil, fmt.Errorf("unfilled type: %s", k.Name)
+	}
+	return k.t.Decode(r)
+}
+
+func (k *Knot) EncodeType(tdt *TypeDefinitionTable) ([]byte, error) {
+	if k.t == nil {
+		return nil, fmt.Errorf("unfilled type: %s", k.Name)
+	}
+	if !k.isComposite() {
+		return k.t.EncodeType(tdt)
+	}
+	idx, ok := tdt.Indexes[k.key()]
+	if !ok {
+		return nil, fmt.Errorf("missing type index for: %s", k.Name)
+	}
+	return leb128.EncodeSigned(big.NewInt(int64(idx)))
+}
+
+func (k *Knot) EncodeValue(v interface{}) ([]byte, error) {
+	if k.t == nil {
+		return nil, fmt.Errorf("unfilled type: %s", k.Name)
+	}
+	return k.t.EncodeValue(v)
+}
+
+func (k *Knot) String() string {
+	return k.Name
+}
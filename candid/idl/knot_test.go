@@ -0,0 +1,110 @@
+package idl_test
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/icpfans-xyz/agent-go/candid/idl"
+)
+
+func roundTrip(types []idl.Type, args []interface{}) {
+	bs, err := idl.Encode(types, args)
+	if err != nil {
+		fmt.Println("enc:", err)
+		return
+	}
+	fmt.Printf("%x\n", bs)
+	ts, vs, err := idl.Decode(bs)
+	if err != nil {
+		fmt.Println("dec:", err)
+		return
+	}
+	fmt.Println(ts)
+	fmt.Println(show(vs))
+}
+
+// show formats decoded values, following variants.
+func show(v interface{}) string {
+	switch v := v.(type) {
+	case *idl.FieldValue:
+		return fmt.Sprintf("%s(%s)", v.Name, show(v.Value))
+	case []interface{}:
+		var s []string
+		for _, v := range v {
+			s = append(s, show(v))
+		}
+		return fmt.Sprintf("%v", s)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func ExampleKnot_list() {
+	list := idl.NewKnot("List")
+	list.Fill(idl.NewOpt(idl.NewRec(map[string]idl.Type{
+		"head": new(idl.Nat),
+		"tail": list,
+	})))
+	fmt.Println(list.Type())
+	roundTrip([]idl.Type{list}, []interface{}{
+		map[string]interface{}{
+			"head": big.NewInt(1),
+			"tail": map[string]interface{}{
+				"head": big.NewInt(2),
+				"tail": nil,
+			},
+		},
+	})
+	// Output:
+	// opt record {head:nat; tail:List}
+	// 4449444c036e016c02a0d2aca8047d90eddae704006e0101000101010200
+	// [opt record {1158359328:nat; 1291237008:table0}]
+	// [map[1158359328:1 1291237008:map[1158359328:2 1291237008:<nil>]]]
+}
+
+func ExampleKnot_tree() {
+	tree := idl.NewKnot("Tree")
+	tree.Fill(idl.NewVariant(map[string]idl.Type{
+		"leaf": new(idl.Int),
+		"node": idl.NewVec(tree),
+	}))
+	roundTrip([]idl.Type{tree}, []interface{}{
+		idl.FieldValue{Name: "node", Value: []interface{}{
+			idl.FieldValue{Name: "leaf", Value: big.NewInt(1)},
+			idl.FieldValue{Name: "node", Value: []interface{}{}},
+		}},
+	})
+	// Output:
+	// 4449444c036b029e87c0bd047c8294a8c804016d006b029e87c0bd047c8294a8c804010100010200010100
+	// [variant {1202717598:int; 1225394690:vec table0}]
+	// [1225394690([1202717598(1) 1225394690([])])]
+}
+
+func ExampleKnot_mutual() {
+	a := idl.NewKnot("A")
+	b := idl.NewKnot("B")
+	a.Fill(idl.NewOpt(idl.NewRec(map[string]idl.Type{"b": b})))
+	b.Fill(idl.NewOpt(idl.NewRec(map[string]idl.Type{"a": a})))
+	roundTrip([]idl.Type{a, b}, []interface{}{
+		map[string]interface{}{"b": map[string]interface{}{"a": nil}},
+		nil,
+	})
+	// Output:
+	// 4449444c066e046e026c0161006e026c0162016e0402000101010000
+	// [opt record {98:opt record {97:table0}} opt record {97:table0}]
+	// [map[98:map[97:<nil>]] <nil>]
+}
+
+func ExampleKnot_Fill() {
+	a := idl.NewKnot("A")
+	fmt.Println(a.Fill(a))
+	b := idl.NewKnot("B")
+	fmt.Println(b.Fill(a))
+	fmt.Println(a.Fill(b))
+	fmt.Println(a.Type())
+	// Output:
+	// knot A refers to itself
+	// <nil>
+	// knot A refers to itself
+	// <nil>
+}
@@ -0,0 +1,45 @@
+package idl
+
+import (
+	"fmt"
+)
+
+// Labels maps the ids of record and variant fields back to their names, which
+// are lost on the wire. Fields that are not in the dictionary keep their id as
+// name, so that numeric labels such as the fields of tuples stay distinct.
+type Labels map[string]string
+
+// NewLabels returns the dictionary of the names. Names that have the same id
+// are an error, since they cannot be told apart once encoded.
+func NewLabels(names ...string) (Labels, error) {
+	labels := make(Labels)
+	for _, name := range names {
+		id := LabelId(name).String()
+		if other, ok := labels[id]; ok && other != name {
+			return nil, fmt.Errorf("label collision: %s and %s have the same id %s", other, name, id)
+		}
+		labels[id] = name
+	}
+	return labels, nil
+}
+
+// Name returns the name of the field with the given id.
+func (l Labels) Name(id string) string {
+	if name, ok := l[id]; ok {
+		return name
+	}
+	return id
+}
+
+// checkLabels returns an error if two of the fields have the same id.
+func checkLabels(fields []Field) error {
+	ids := make(map[string]string)
+	for _, f := range fields {
+		id := LabelId(f.Name).String()
+		if other, ok := ids[id]; ok {
+			return fmt.Errorf("label collision: %s and %s have the same id %s", other, f.Name, id)
+		}
+		ids[id] = f.Name
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package idl_test
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/icpfans-xyz/agent-go/candid/idl"
+)
+
+func ExampleDecodeLabeled() {
+	bs, _ := idl.Encode([]idl.Type{idl.NewRec(map[string]idl.Type{
+		"name":   new(idl.Text),
+		"amount": new(idl.Nat),
+		"status": idl.NewVariant(map[string]idl.Type{
+			"pending":   new(idl.Null),
+			"completed": idl.Nat64(),
+		}),
+	})}, []interface{}{map[string]interface{}{
+		"name":   "alice",
+		"amount": big.NewInt(10),
+		"status": idl.FieldValue{Name: "pending"},
+	}})
+	labels, _ := idl.NewLabels("name", "amount", "status", "pending", "completed")
+	ts, vs, err := idl.DecodeLabeled(bs, labels)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(ts)
+	rec := vs[0].(map[string]interface{})
+	fmt.Println(rec["name"], rec["amount"], show(rec["status"]))
+	// Output:
+	// [record {status:variant {pending:null; completed:nat64}; name:text; amount:nat}]
+	// alice 10 pending(<nil>)
+}
+
+func ExampleLabelId() {
+	fmt.Println(idl.LabelId("0"), idl.LabelId("1"), idl.LabelId("a"), idl.LabelId("97"))
+	bs, _ := idl.Encode([]idl.Type{idl.NewRec(map[string]idl.Type{
+		"0": new(idl.Text),
+		"1": new(idl.Bool),
+	})}, []interface{}{map[string]interface{}{
+		"0": "x",
+		"1": true,
+	}})
+	fmt.Printf("%x\n", bs)
+	ts, vs, _ := idl.Decode(bs)
+	fmt.Println(ts, vs)
+
+	_, err := idl.NewLabels("a", "97")
+	fmt.Println(err)
+	_, err = idl.Encode([]idl.Type{idl.NewRec(map[string]idl.Type{
+		"a":  new(idl.Text),
+		"97": new(idl.Text),
+	})}, []interface{}{map[string]interface{}{"a": "x", "97": "y"}})
+	fmt.Println(err != nil)
+	// Output:
+	// 0 1 97 97
+	// 4449444c016c020071017e0100017801
+	// [record {0:text; 1:bool}] [map[0:x 1:true]]
+	// label collision: a and 97 have the same id 97
+	// true
+}
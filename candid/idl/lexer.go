@@ -0,0 +1,285 @@
+package idl
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// TokenKind is the kind of a Token.
+type TokenKind int
+
+const (
+	TokenEOF TokenKind = iota
+	TokenIdent
+	TokenNumber
+	TokenText
+	TokenSymbol
+)
+
+func (k TokenKind) String() string {
+	switch k {
+	case TokenIdent:
+		return "identifier"
+	case TokenNumber:
+		return "number"
+	case TokenText:
+		return "text"
+	case TokenSymbol:
+		return "symbol"
+	default:
+		return "end of input"
+	}
+}
+
+// Position is a position in the textual format, with lines and columns
+// starting at 1.
+type Position struct {
+	Line   int
+	Column int
+}
+
+// Token is a token of the textual format of Candid, shared by values and
+// .did files.
+type Token struct {
+	Kind TokenKind
+	// The value of the token. Texts are unescaped, and escapes can produce
+	// bytes that are not UTF-8, for blobs: texts must be checked with
+	// utf8.ValidString where they are used as text.
+	Value string
+	Pos   Position
+}
+
+func (t Token) String() string {
+	switch t.Kind {
+	case TokenEOF:
+		return "end of input"
+	case TokenText:
+		return quoteText(t.Value)
+	}
+	return fmt.Sprintf("%q", t.Value)
+}
+
+// Nat returns the value of a number token that is a natural number:
+// decimal, or hexadecimal with the prefix 0x. A leading 0 is not octal.
+func (t Token) Nat() (*big.Int, bool) {
+	if t.Kind != TokenNumber {
+		return nil, false
+	}
+	s, base := strings.ReplaceAll(t.Value, "_", ""), 10
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s, base = s[2:], 16
+	}
+	return new(big.Int).SetString(s, base)
+}
+
+// SyntaxError is an error in the textual format.
+type SyntaxError struct {
+	Pos     Position
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Pos.Line, e.Pos.Column, e.Message)
+}
+
+// Lexer splits the textual format into tokens, skipping white space and
+// comments. A Lexer can be copied to backtrack.
+type Lexer struct {
+	src string
+	off int
+	pos Position
+}
+
+// NewLexer returns a lexer of the text.
+func NewLexer(src string) *Lexer {
+	return &Lexer{src: src, pos: Position{Line: 1, Column: 1}}
+}
+
+func (l *Lexer) errorf(pos Position, format string, args ...interface{}) error {
+	return &SyntaxError{Pos: pos, Message: fmt.Sprintf(format, args...)}
+}
+
+func (l *Lexer) peekByte(i int) byte {
+	if l.off+i < len(l.src) {
+		return l.src[l.off+i]
+	}
+	return 0
+}
+
+func (l *Lexer) advance(n int) {
+	for _, r := range l.src[l.off : l.off+n] {
+		if r == '\n' {
+			l.pos.Line++
+			l.pos.Column = 1
+		} else {
+			l.pos.Column++
+		}
+	}
+	l.off += n
+}
+
+// skip skips white space and comments. Block comments can be nested.
+func (l *Lexer) skip() error {
+	for l.off < len(l.src) {
+		switch c := l.peekByte(0); {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			l.advance(1)
+		case c == '/' && l.peekByte(1) == '/':
+			end := strings.IndexByte(l.src[l.off:], '\n')
+			if end < 0 {
+				end = len(l.src) - l.off
+			}
+			l.advance(end)
+		case c == '/' && l.peekByte(1) == '*':
+			start := l.pos
+			l.advance(2)
+			for depth := 1; depth > 0; {
+				switch {
+				case l.off >= len(l.src):
+					return l.errorf(start, "unterminated comment")
+				case l.peekByte(0) == '/' && l.peekByte(1) == '*':
+					depth++
+					l.advance(2)
+				case l.peekByte(0) == '*' && l.peekByte(1) == '/':
+					depth--
+					l.advance(2)
+				default:
+					l.advance(1)
+				}
+			}
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '_'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// Next returns the next token, of kind TokenEOF at the end of the input.
+func (l *Lexer) Next() (Token, error) {
+	if err := l.skip(); err != nil {
+		return Token{}, err
+	}
+	pos := l.pos
+	if l.off >= len(l.src) {
+		return Token{Kind: TokenEOF, Pos: pos}, nil
+	}
+	c := l.peekByte(0)
+	switch {
+	case isLetter(c):
+		n := 1
+		for isLetter(l.peekByte(n)) || isDigit(l.peekByte(n)) {
+			n++
+		}
+		value := l.src[l.off : l.off+n]
+		l.advance(n)
+		return Token{Kind: TokenIdent, Value: value, Pos: pos}, nil
+	case isDigit(c):
+		n := 1
+		if c == '0' && (l.peekByte(1) == 'x' || l.peekByte(1) == 'X') {
+			n = 2
+			for isHexDigit(l.peekByte(n)) || l.peekByte(n) == '_' {
+				n++
+			}
+		} else {
+			// Decimal numbers, which can be floats.
+			for {
+				d := l.peekByte(n)
+				if isDigit(d) || d == '_' || d == '.' || d == 'e' || d == 'E' ||
+					(d == '+' || d == '-') && (l.peekByte(n-1) == 'e' || l.peekByte(n-1) == 'E') {
+					n++
+					continue
+				}
+				break
+			}
+		}
+		value := l.src[l.off : l.off+n]
+		l.advance(n)
+		return Token{Kind: TokenNumber, Value: value, Pos: pos}, nil
+	case c == '"':
+		value, err := l.text()
+		if err != nil {
+			return Token{}, err
+		}
+		return Token{Kind: TokenText, Value: value, Pos: pos}, nil
+	case c == '-' && l.peekByte(1) == '>':
+		l.advance(2)
+		return Token{Kind: TokenSymbol, Value: "->", Pos: pos}, nil
+	case strings.IndexByte("(){};:=,.+-", c) >= 0:
+		l.advance(1)
+		return Token{Kind: TokenSymbol, Value: string(c), Pos: pos}, nil
+	}
+	r, _ := utf8.DecodeRuneInString(l.src[l.off:])
+	return Token{}, l.errorf(pos, "unexpected character %q", r)
+}
+
+// text reads a text literal, with the escapes of Candid: \n, \r, \t, \\, \",
+// \', two hex digits for a byte and \u{...} for a code point.
+func (l *Lexer) text() (string, error) {
+	start := l.pos
+	l.advance(1)
+	var b strings.Builder
+	for {
+		if l.off >= len(l.src) {
+			return "", l.errorf(start, "unterminated text")
+		}
+		c := l.peekByte(0)
+		switch c {
+		case '"':
+			l.advance(1)
+			return b.String(), nil
+		case '\n':
+			return "", l.errorf(start, "unterminated text")
+		case '\\':
+			pos := l.pos
+			switch e := l.peekByte(1); {
+			case e == 'n':
+				b.WriteByte('\n')
+				l.advance(2)
+			case e == 'r':
+				b.WriteByte('\r')
+				l.advance(2)
+			case e == 't':
+				b.WriteByte('\t')
+				l.advance(2)
+			case e == '\\' || e == '"' || e == '\'':
+				b.WriteByte(e)
+				l.advance(2)
+			case isHexDigit(e) && isHexDigit(l.peekByte(2)):
+				v, _ := strconv.ParseUint(l.src[l.off+1:l.off+3], 16, 8)
+				b.WriteByte(byte(v))
+				l.advance(3)
+			case e == 'u' && l.peekByte(2) == '{':
+				end := strings.IndexByte(l.src[l.off:], '}')
+				if end < 0 {
+					return "", l.errorf(pos, "invalid unicode escape")
+				}
+				v, err := strconv.ParseUint(strings.ReplaceAll(l.src[l.off+3:l.off+end], "_", ""), 16, 32)
+				if err != nil || !utf8.ValidRune(rune(v)) {
+					return "", l.errorf(pos, "invalid unicode escape")
+				}
+				b.WriteRune(rune(v))
+				l.advance(end + 1)
+			default:
+				return "", l.errorf(pos, "invalid escape")
+			}
+		default:
+			b.WriteByte(c)
+			l.advance(1)
+		}
+	}
+}
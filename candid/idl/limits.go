@@ -0,0 +1,134 @@
+package idl
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/aviate-labs/leb128"
+)
+
+// The limits of a DecoderConfig, as reported by LimitError.
+const (
+	LimitSize      = "size"
+	LimitTypeTable = "type table entries"
+	LimitDepth     = "depth"
+	LimitVecLength = "vector length"
+	LimitCost      = "cost"
+)
+
+// LimitError is returned when a message exceeds a limit of the decoder.
+type LimitError struct {
+	// The limit that is exceeded, one of the Limit constants.
+	Limit string
+	Max   int
+}
+
+func (e LimitError) Error() string {
+	return fmt.Sprintf("candid: message exceeds the %s limit of %d", e.Limit, e.Max)
+}
+
+// DecoderConfig limits the resources used to decode a message, which can
+// come from an untrusted canister or proxy. A limit of zero is no limit.
+type DecoderConfig struct {
+	// The size of the message in bytes.
+	MaxSize int
+	// The number of entries of the type definition table.
+	MaxTypeTableEntries int
+	// The nesting depth of values, arguments being at depth 1.
+	MaxDepth int
+	// The number of elements of a vector.
+	MaxVecLength int
+	// The cost of decoding the values, one unit per value, including the
+	// elements of vectors and the fields of records. Values of types like
+	// null take no space in the message, so this bounds the work and memory
+	// needed for something like a vec null of a billion elements.
+	MaxCost int
+}
+
+// DefaultDecoderConfig is used by Decode, DecodeLabeled and DecodeAs. It
+// allows messages far above the size limits of the IC.
+var DefaultDecoderConfig = DecoderConfig{
+	MaxTypeTableEntries: 10000,
+	MaxDepth:            10000,
+	MaxCost:             4000000,
+}
+
+// Decode decodes the arguments like Decode, within the limits.
+func (c DecoderConfig) Decode(bs []byte) ([]Type, []interface{}, error) {
+	return decode(bs, nil, c)
+}
+
+// DecodeLabeled decodes the arguments like DecodeLabeled, within the limits.
+func (c DecoderConfig) DecodeLabeled(bs []byte, labels Labels) ([]Type, []interface{}, error) {
+	return decode(bs, labels, c)
+}
+
+// limiter checks that the values of a message are within the limits before
+// they are decoded.
+type limiter struct {
+	config DecoderConfig
+	cost   int
+}
+
+func (l *limiter) check(t Type, r *bytes.Reader, depth int) error {
+	if l.config.MaxDepth != 0 && depth > l.config.MaxDepth {
+		return &LimitError{Limit: LimitDepth, Max: l.config.MaxDepth}
+	}
+	l.cost++
+	if l.config.MaxCost != 0 && l.cost > l.config.MaxCost {
+		return &LimitError{Limit: LimitCost, Max: l.config.MaxCost}
+	}
+	switch t := resolve(t).(type) {
+	case *Opt:
+		b, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		switch b {
+		case 0x00:
+			return nil
+		case 0x01:
+			return l.check(t.Type, r, depth+1)
+		}
+		return fmt.Errorf("invalid option value")
+	case *Vec:
+		n, err := leb128.DecodeUnsigned(r)
+		if err != nil {
+			return err
+		}
+		if l.config.MaxVecLength != 0 && (!n.IsInt64() || n.Int64() > int64(l.config.MaxVecLength)) {
+			return &LimitError{Limit: LimitVecLength, Max: l.config.MaxVecLength}
+		}
+		if !n.IsInt64() {
+			return &FormatError{
+				Description: fmt.Sprintf("vec: invalid length: %s", n),
+			}
+		}
+		for i := int64(0); i < n.Int64(); i++ {
+			if err := l.check(t.Type, r, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *Rec:
+		for _, f := range t.Fields {
+			if err := l.check(f.Type, r, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *Variant:
+		i, err := leb128.DecodeUnsigned(r)
+		if err != nil {
+			return err
+		}
+		if !i.IsInt64() || i.Int64() >= int64(len(t.Fields)) {
+			return fmt.Errorf("invalid variant index: %v", i)
+		}
+		return l.check(t.Fields[i.Int64()].Type, r, depth+1)
+	}
+	// Other values do not nest and only take as much memory as their size
+	// in the message.
+	_, err := t.Decode(r)
+	return err
+}
@@ -0,0 +1,52 @@
+package idl_test
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/icpfans-xyz/agent-go/candid/idl"
+)
+
+func ExampleDecoderConfig() {
+	// A vec null of a billion elements takes 14 bytes.
+	bs, _ := hex.DecodeString("4449444c016d7f01008094ebdc03")
+	_, _, err := idl.Decode(bs)
+	fmt.Println(err)
+	var limit *idl.LimitError
+	fmt.Println(errors.As(err, &limit), limit.Limit)
+
+	// A text of 100 bytes cannot be longer than the message.
+	bs, _ = hex.DecodeString("4449444c00017164616263")
+	_, _, err = idl.Decode(bs)
+	var format *idl.FormatError
+	fmt.Println(err, errors.As(err, &format))
+
+	bs, _ = idl.Encode([]idl.Type{
+		idl.NewVec(idl.NewOpt(idl.NewOpt(new(idl.Nat)))),
+	}, []interface{}{
+		[]interface{}{big.NewInt(1), big.NewInt(2), big.NewInt(3)},
+	})
+	for _, c := range []idl.DecoderConfig{
+		{MaxSize: 10},
+		{MaxTypeTableEntries: 2},
+		{MaxDepth: 3},
+		{MaxVecLength: 2},
+		{MaxCost: 9},
+		{MaxCost: 10},
+	} {
+		_, vs, err := c.Decode(bs)
+		fmt.Println(vs, err)
+	}
+	// Output:
+	// candid: message exceeds the cost limit of 4000000
+	// true cost
+	// () text: too long true
+	// [] candid: message exceeds the size limit of 10
+	// [] candid: message exceeds the type table entries limit of 2
+	// [] candid: message exceeds the depth limit of 3
+	// [] candid: message exceeds the vector length limit of 2
+	// [] candid: message exceeds the cost limit of 9
+	// [[1 2 3]] <nil>
+}
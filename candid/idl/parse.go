@@ -0,0 +1,554 @@
+package idl
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mix-labs/IC-Go/utils/principal"
+)
+
+// ParseArgs parses arguments in the textual format of Candid, like
+// `(record { name = "x"; amount = 10 : nat }, opt true)`, as values of the
+// given types that can be encoded.
+func ParseArgs(types []Type, text string) ([]interface{}, error) {
+	p, err := newValueParser(text)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var values []interface{}
+	for i := 0; !p.is(")"); i++ {
+		if i >= len(types) {
+			return nil, p.errorf("too many arguments, expected %d", len(types))
+		}
+		v, err := p.value(types[i])
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+		if !p.is(")") {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	for i := len(values); i < len(types); i++ {
+		if !isOptional(types[i]) {
+			return nil, p.errorf("missing argument of type %s", types[i])
+		}
+		values = append(values, nil)
+	}
+	return values, p.end()
+}
+
+// ParseValue parses a single value in the textual format of Candid.
+func ParseValue(t Type, text string) (interface{}, error) {
+	p, err := newValueParser(text)
+	if err != nil {
+		return nil, err
+	}
+	v, err := p.value(t)
+	if err != nil {
+		return nil, err
+	}
+	return v, p.end()
+}
+
+// valueParser is a recursive descent parser of textual values, directed by
+// the expected types.
+type valueParser struct {
+	tokens []Token
+	i      int
+}
+
+func newValueParser(text string) (*valueParser, error) {
+	var tokens []Token
+	l := NewLexer(text)
+	for {
+		tok, err := l.Next()
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, tok)
+		if tok.Kind == TokenEOF {
+			return &valueParser{tokens: tokens}, nil
+		}
+	}
+}
+
+func (p *valueParser) tok() Token {
+	return p.tokens[p.i]
+}
+
+func (p *valueParser) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Pos: p.tok().Pos, Message: fmt.Sprintf(format, args...)}
+}
+
+func (p *valueParser) unexpected(expected string) error {
+	return p.errorf("expected %s, found %s", expected, p.tok())
+}
+
+func (p *valueParser) next() Token {
+	t := p.tok()
+	if t.Kind != TokenEOF {
+		p.i++
+	}
+	return t
+}
+
+// is reports whether the current token is the symbol or keyword.
+func (p *valueParser) is(value string) bool {
+	t := p.tok()
+	return (t.Kind == TokenSymbol || t.Kind == TokenIdent) && t.Value == value
+}
+
+func (p *valueParser) expect(value string) error {
+	if !p.is(value) {
+		return p.unexpected(strconv.Quote(value))
+	}
+	p.next()
+	return nil
+}
+
+func (p *valueParser) end() error {
+	if p.tok().Kind != TokenEOF {
+		return p.unexpected("end of input")
+	}
+	return nil
+}
+
+// text parses a text, which must be valid UTF-8.
+func (p *valueParser) text() (string, error) {
+	if p.tok().Kind != TokenText {
+		return "", p.unexpected("text")
+	}
+	if !utf8.ValidString(p.tok().Value) {
+		return "", p.errorf("text is not valid UTF-8")
+	}
+	return p.next().Value, nil
+}
+
+// bytes parses a text as bytes, for blobs.
+func (p *valueParser) bytes() (string, error) {
+	if p.tok().Kind != TokenText {
+		return "", p.unexpected("text")
+	}
+	return p.next().Value, nil
+}
+
+// value parses a value of the type, which may be parenthesized and
+// annotated with its type.
+func (p *valueParser) value(t Type) (interface{}, error) {
+	if p.is("(") {
+		p.next()
+		v, err := p.value(t)
+		if err != nil {
+			return nil, err
+		}
+		return v, p.expect(")")
+	}
+	v, err := p.bareValue(resolve(t))
+	if err != nil {
+		return nil, err
+	}
+	if p.is(":") {
+		p.next()
+		if err := p.annotation(resolve(t)); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// annotation checks that a type annotation is the expected type. Only
+// primitive types can be annotated.
+func (p *valueParser) annotation(t Type) error {
+	tok := p.tok()
+	if tok.Kind != TokenIdent {
+		return p.unexpected("type")
+	}
+	if _, ok := t.(PrimType); !ok || tok.Value != t.String() {
+		return p.errorf("type annotation %s does not match %s", tok.Value, t)
+	}
+	p.next()
+	return nil
+}
+
+func (p *valueParser) bareValue(t Type) (interface{}, error) {
+	switch t := t.(type) {
+	case *Null:
+		return nil, p.expect("null")
+	case *Reserved:
+		return nil, p.skip()
+	case *Bool:
+		switch {
+		case p.is("true"):
+			p.next()
+			return true, nil
+		case p.is("false"):
+			p.next()
+			return false, nil
+		}
+		return nil, p.unexpected("bool")
+	case *Nat:
+		return p.integer(false, t.Base)
+	case *Int:
+		return p.integer(true, t.Base)
+	case *Float:
+		return p.float(t)
+	case *Text:
+		return p.text()
+	case *Principal:
+		if err := p.expect("principal"); err != nil {
+			return nil, err
+		}
+		return p.principal()
+	case *Service:
+		if err := p.expect("service"); err != nil {
+			return nil, err
+		}
+		return p.principal()
+	case *Func:
+		if err := p.expect("func"); err != nil {
+			return nil, err
+		}
+		pid, err := p.principal()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("."); err != nil {
+			return nil, err
+		}
+		method, err := p.label()
+		if err != nil {
+			return nil, err
+		}
+		return PrincipalMethod{Principal: pid, Method: method}, nil
+	case *Opt:
+		if p.is("null") {
+			p.next()
+			return nil, nil
+		}
+		if err := p.expect("opt"); err != nil {
+			return nil, err
+		}
+		return p.value(t.Type)
+	case *Vec:
+		return p.vec(t)
+	case *Rec:
+		return p.record(t)
+	case *Variant:
+		return p.variant(t)
+	}
+	return nil, p.errorf("cannot parse values of type %s", t)
+}
+
+// integer parses a number with an optional sign, which is checked to fit
+// the given size in bits, if any.
+func (p *valueParser) integer(signed bool, bits uint8) (*big.Int, error) {
+	sign := ""
+	if p.is("+") || p.is("-") {
+		sign = p.next().Value
+	}
+	tok := p.tok()
+	if tok.Kind != TokenNumber {
+		return nil, p.unexpected("number")
+	}
+	n, ok := tok.Nat()
+	if !ok {
+		return nil, p.errorf("invalid integer %s", tok.Value)
+	}
+	if sign == "-" {
+		n.Neg(n)
+	}
+	min, max := new(big.Int), new(big.Int)
+	switch {
+	case signed && bits != 0:
+		max.Lsh(big.NewInt(1), uint(bits-1))
+		min.Neg(max)
+		max.Sub(max, big.NewInt(1))
+	case !signed:
+		max.Lsh(big.NewInt(1), uint(bits))
+		max.Sub(max, big.NewInt(1))
+	}
+	if !signed && n.Sign() < 0 || bits != 0 && (n.Cmp(min) < 0 || n.Cmp(max) > 0) {
+		return nil, p.errorf("%s%s is out of range", sign, tok.Value)
+	}
+	p.next()
+	return n, nil
+}
+
+// float parses a float, rounded to the precision of the type. Floats that
+// overflow the type are rejected rather than made infinite.
+func (p *valueParser) float(t *Float) (*big.Float, error) {
+	sign := ""
+	if p.is("+") || p.is("-") {
+		sign = p.next().Value
+	}
+	tok := p.tok()
+	if tok.Kind != TokenNumber {
+		return nil, p.unexpected("number")
+	}
+	prec := uint(53)
+	if t.Base == 32 {
+		prec = 24
+	}
+	f, _, err := big.ParseFloat(sign+strings.ReplaceAll(tok.Value, "_", ""), 0, prec, big.ToNearestEven)
+	if err != nil {
+		return nil, p.errorf("invalid float %s", tok.Value)
+	}
+	var v float64
+	if t.Base == 32 {
+		f32, _ := f.Float32()
+		v = float64(f32)
+	} else {
+		v, _ = f.Float64()
+	}
+	if math.IsInf(v, 0) {
+		return nil, p.errorf("%s%s is out of range of %s", sign, tok.Value, t)
+	}
+	p.next()
+	return big.NewFloat(v), nil
+}
+
+func (p *valueParser) principal() (principal.Principal, error) {
+	tok := p.tok()
+	s, err := p.text()
+	if err != nil {
+		return nil, err
+	}
+	pid, err := principal.Decode(s)
+	if err != nil {
+		p.i--
+		return nil, p.errorf("invalid principal %s", quoteText(tok.Value))
+	}
+	return pid, nil
+}
+
+// label parses a field or method name: an identifier, a number or a text.
+func (p *valueParser) label() (string, error) {
+	tok := p.tok()
+	switch tok.Kind {
+	case TokenIdent:
+		p.next()
+		return tok.Value, nil
+	case TokenText:
+		return p.text()
+	case TokenNumber:
+		n, ok := tok.Nat()
+		if !ok || n.BitLen() > 32 {
+			return "", p.errorf("invalid field id %s", tok.Value)
+		}
+		p.next()
+		return n.String(), nil
+	}
+	return "", p.unexpected("label")
+}
+
+func (p *valueParser) vec(t *Vec) (interface{}, error) {
+	if n, ok := resolve(t.Type).(*Nat); ok && n.Base == 8 && p.is("blob") {
+		p.next()
+		s, err := p.bytes()
+		if err != nil {
+			return nil, err
+		}
+		var vs []interface{}
+		for i := 0; i < len(s); i++ {
+			vs = append(vs, big.NewInt(int64(s[i])))
+		}
+		return vs, nil
+	}
+	if err := p.expect("vec"); err != nil {
+		return nil, err
+	}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var vs []interface{}
+	for !p.is("}") {
+		v, err := p.value(t.Type)
+		if err != nil {
+			return nil, err
+		}
+		vs = append(vs, v)
+		if !p.is("}") {
+			if err := p.expect(";"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	p.next()
+	return vs, nil
+}
+
+// field returns the field of the type with the given label.
+func field(fields []Field, label string) (Field, bool) {
+	id := LabelId(label)
+	for _, f := range fields {
+		if LabelId(f.Name).Cmp(id) == 0 {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
+
+func (p *valueParser) record(t *Rec) (interface{}, error) {
+	if err := p.expect("record"); err != nil {
+		return nil, err
+	}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	rec := make(map[string]interface{})
+	for i := 0; !p.is("}"); i++ {
+		// A field without label is numbered after the previous one.
+		label := fmt.Sprint(i)
+		if p.i+1 < len(p.tokens) && p.tokens[p.i+1].Kind == TokenSymbol && p.tokens[p.i+1].Value == "=" {
+			l, err := p.label()
+			if err != nil {
+				return nil, err
+			}
+			label = l
+			p.next()
+		}
+		f, ok := field(t.Fields, label)
+		if !ok {
+			return nil, p.errorf("unknown field %s of %s", label, t)
+		}
+		if _, ok := rec[f.Name]; ok {
+			return nil, p.errorf("duplicate field %s", label)
+		}
+		v, err := p.value(f.Type)
+		if err != nil {
+			return nil, err
+		}
+		rec[f.Name] = v
+		if n, ok := new(big.Int).SetString(LabelId(label).String(), 10); ok && n.IsInt64() {
+			i = int(n.Int64())
+		}
+		if !p.is("}") {
+			if err := p.expect(";"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	p.next()
+	for _, f := range t.Fields {
+		if _, ok := rec[f.Name]; ok {
+			continue
+		}
+		if !isOptional(f.Type) {
+			return nil, p.errorf("missing field %s of %s", f.Name, t)
+		}
+		rec[f.Name] = nil
+	}
+	return rec, nil
+}
+
+func (p *valueParser) variant(t *Variant) (interface{}, error) {
+	if err := p.expect("variant"); err != nil {
+		return nil, err
+	}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	label, err := p.label()
+	if err != nil {
+		return nil, err
+	}
+	f, ok := field(t.Fields, label)
+	if !ok {
+		p.i--
+		return nil, p.errorf("unknown field %s of %s", label, t)
+	}
+	fv := FieldValue{Name: f.Name}
+	if p.is("=") {
+		p.next()
+		v, err := p.value(f.Type)
+		if err != nil {
+			return nil, err
+		}
+		fv.Value = v
+	} else if _, ok := resolve(f.Type).(*Null); !ok {
+		return nil, p.unexpected(`"="`)
+	}
+	if p.is(";") {
+		p.next()
+	}
+	return fv, p.expect("}")
+}
+
+// skip parses a value of any type, for reserved.
+func (p *valueParser) skip() error {
+	switch tok := p.next(); {
+	case tok.Kind == TokenNumber || tok.Kind == TokenText:
+	case tok.Value == "+" || tok.Value == "-":
+		if p.tok().Kind != TokenNumber {
+			return p.unexpected("number")
+		}
+		p.next()
+	case tok.Value == "null" || tok.Value == "true" || tok.Value == "false":
+	case tok.Value == "opt":
+		return p.skip()
+	case tok.Value == "blob":
+		_, err := p.bytes()
+		return err
+	case tok.Value == "principal" || tok.Value == "service":
+		_, err := p.text()
+		return err
+	case tok.Value == "func":
+		if _, err := p.text(); err != nil {
+			return err
+		}
+		if err := p.expect("."); err != nil {
+			return err
+		}
+		_, err := p.label()
+		return err
+	case tok.Value == "(":
+		if err := p.skip(); err != nil {
+			return err
+		}
+		return p.expect(")")
+	case tok.Value == "vec" || tok.Value == "record" || tok.Value == "variant":
+		if err := p.expect("{"); err != nil {
+			return err
+		}
+		for !p.is("}") {
+			if p.i+1 < len(p.tokens) && p.tokens[p.i+1].Value == "=" {
+				p.i += 2
+			}
+			if tok.Value == "variant" && (p.is("}") || p.is(";")) {
+				// A variant field of type null.
+			} else if err := p.skip(); err != nil {
+				return err
+			}
+			if !p.is("}") {
+				if err := p.expect(";"); err != nil {
+					return err
+				}
+			}
+		}
+		p.next()
+	default:
+		p.i--
+		return p.unexpected("value")
+	}
+	if p.is(":") {
+		p.next()
+		if p.tok().Kind != TokenIdent {
+			return p.unexpected("type")
+		}
+		p.next()
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package idl_test
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/icpfans-xyz/agent-go/candid/idl"
+)
+
+func ExampleParseArgs() {
+	types := []idl.Type{
+		idl.NewRec(map[string]idl.Type{
+			"name":   new(idl.Text),
+			"amount": new(idl.Nat),
+			"memo":   idl.NewOpt(idl.Nat64()),
+		}),
+		idl.NewVariant(map[string]idl.Type{
+			"Ok":  idl.NewVec(idl.Nat8()),
+			"Err": new(idl.Text),
+		}),
+		new(idl.Principal),
+	}
+	vs, err := idl.ParseArgs(types, `(
+		record { name = "x"; amount = 1_000 : nat },
+		variant { Ok = blob "\de\ad" }, // a comment
+		principal "aaaaa-aa",
+	)`)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	s, _ := idl.FormatArgs(types, vs)
+	fmt.Println(s)
+	// Output:
+	// (record { memo = null; name = "x"; amount = 1000 }, variant { Ok = blob "\de\ad" }, principal "aaaaa-aa")
+}
+
+func ExampleParseValue() {
+	tuple := idl.NewRec(map[string]idl.Type{
+		"0": idl.Int8(),
+		"1": idl.NewVec(idl.NewOpt(new(idl.Bool))),
+	})
+	v, err := idl.ParseValue(tuple, `record { -0x80; vec { opt true; null } }`)
+	fmt.Println(show(v), err)
+	s, _ := idl.FormatValue(tuple, v)
+	fmt.Println(s)
+	// Output:
+	// map[0:-128 1:[true <nil>]] <nil>
+	// record { -128 : int8; vec { opt true; null } }
+}
+
+func ExampleParseValue_errors() {
+	_, err := idl.ParseValue(idl.Nat8(), `256`)
+	fmt.Println(err)
+	_, err = idl.ParseValue(idl.Nat8(), `1 : nat16`)
+	fmt.Println(err)
+	_, err = idl.ParseValue(idl.NewRec(map[string]idl.Type{
+		"a": new(idl.Text),
+	}), "record {\n  b = 1 }")
+	fmt.Println(err)
+	// Escapes can only produce bytes that are not UTF-8 in blobs.
+	_, err = idl.ParseValue(new(idl.Text), `"\ff"`)
+	fmt.Println(err)
+	_, err = idl.ParseValue(idl.NewVec(idl.Nat8()), `blob "\ff"`)
+	fmt.Println(err)
+	// Numbers are decimal unless prefixed by 0x.
+	n, _ := idl.ParseValue(new(idl.Nat), `010`)
+	fmt.Println(n)
+	n, _ = idl.ParseValue(new(idl.Int), `-0x10`)
+	fmt.Println(n)
+	// Floats are rounded to their type and cannot overflow it.
+	_, err = idl.ParseValue(idl.Float64(), `1e400`)
+	fmt.Println(err)
+	_, err = idl.ParseValue(idl.Float32(), `-1e39`)
+	fmt.Println(err)
+	f, _ := idl.ParseValue(idl.Float32(), `0.1`)
+	fmt.Println(f.(*big.Float).Text('g', 20))
+	// Output:
+	// 1:1: 256 is out of range
+	// 1:5: type annotation nat16 does not match nat8
+	// 2:7: unknown field b of record {a:text}
+	// 1:1: text is not valid UTF-8
+	// <nil>
+	// 10
+	// -16
+	// 1:1: 1e400 is out of range of float64
+	// 1:2: -1e39 is out of range of float32
+	// 0.10000000149011611938
+}
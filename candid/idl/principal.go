@@ -28,9 +28,12 @@ func (t *Principal) Decode(r *bytes.Reader) (interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
+	if !l.IsInt64() || l.Int64() > int64(r.Len()) {
+		return nil, fmt.Errorf("invalid principal id length: %s", l)
+	}
 	pid := make(principal.Principal, l.Int64())
 	n, err := r.Read(pid)
-	if err != nil {
+	if err != nil && len(pid) != 0 {
 		return nil, err
 	}
 	if n != int(l.Int64()) {
@@ -23,12 +23,15 @@ func NewRec(fields map[string]Type) *Rec {
 		})
 	}
 	sort.Slice(rec.Fields, func(i, j int) bool {
-		return Hash(rec.Fields[i].Name).Cmp(Hash(rec.Fields[j].Name)) < 0
+		return LabelId(rec.Fields[i].Name).Cmp(LabelId(rec.Fields[j].Name)) < 0
 	})
 	return &rec
 }
 
 func (r Rec) AddTypeDefinition(tdt *TypeDefinitionTable) error {
+	if err := checkLabels(r.Fields); err != nil {
+		return err
+	}
 	for _, f := range r.Fields {
 		if err := f.Type.AddTypeDefinition(tdt); err != nil {
 			return err
@@ -45,7 +48,7 @@ func (r Rec) AddTypeDefinition(tdt *TypeDefinitionTable) error {
 	}
 	var vs []byte
 	for _, f := range r.Fields {
-		l, err := leb128.EncodeUnsigned(Hash(f.Name))
+		l, err := leb128.EncodeUnsigned(LabelId(f.Name))
 		if err != nil {
 			return nil
 		}
@@ -0,0 +1,513 @@
+package idl
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/icpfans-xyz/agent-go/principal"
+	icprincipal "github.com/mix-labs/IC-Go/utils/principal"
+)
+
+var (
+	bigIntGoType      = reflect.TypeOf(big.Int{})
+	principalGoType   = reflect.TypeOf(principal.Principal{})
+	emptyStructGoType = reflect.TypeOf(struct{}{})
+)
+
+// Marshal encodes the values as Candid arguments, deriving their Candid types
+// from their Go types:
+//
+//	bool                     bool
+//	string                   text
+//	int8, int16, int32       int8, int16, int32
+//	int, int64               int64
+//	uint8, uint16, uint32    nat8, nat16, nat32
+//	uint, uint64             nat64
+//	float32, float64         float32, float64
+//	big.Int                  int, or nat with the "nat" tag option
+//	principal.Principal      principal
+//	[]T, [n]T                vec T
+//	*T                       opt T
+//	struct                   record
+//
+// The fields of a record are named by their `candid:"name"` tag, or by
+// their Go name without a tag, and are skipped with `candid:"-"`. A struct
+// with a `_ struct{} candid:",variant"` field is a variant: its fields must
+// be pointers, of which exactly one is set, and a *struct{} field is a case
+// without value.
+func Marshal(values ...interface{}) ([]byte, error) {
+	var types []Type
+	var args []interface{}
+	for _, v := range values {
+		rv := reflect.ValueOf(v)
+		if !rv.IsValid() {
+			return nil, fmt.Errorf("cannot marshal nil")
+		}
+		t, err := newTypeBuilder().typeOf(rv.Type(), false)
+		if err != nil {
+			return nil, err
+		}
+		arg, err := valueOf(rv, false)
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, t)
+		args = append(args, arg)
+	}
+	return Encode(types, args)
+}
+
+// MarshalAs encodes the values as Candid arguments of the given types, such
+// as the argument types of a method. The values are converted like in
+// Marshal, so their Go types must match the Candid types.
+func MarshalAs(types []Type, values ...interface{}) ([]byte, error) {
+	if len(types) != len(values) {
+		return nil, fmt.Errorf("expected %d values, got %d", len(types), len(values))
+	}
+	var args []interface{}
+	for i, v := range values {
+		rv := reflect.ValueOf(v)
+		if !rv.IsValid() {
+			args = append(args, nil)
+			continue
+		}
+		_, nat := resolve(types[i]).(*Nat)
+		arg, err := valueOf(rv, nat)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	return Encode(types, args)
+}
+
+// Unmarshal decodes Candid arguments into the values pointed to by ptrs,
+// following the mapping of Marshal. Record fields are matched by name or by
+// hash, and a missing field is allowed for opt (pointer) fields only.
+// Decoded values that do not fit the Go type are an error.
+func Unmarshal(data []byte, ptrs ...interface{}) error {
+	_, vs, err := Decode(data)
+	if err != nil {
+		return err
+	}
+	if len(vs) < len(ptrs) {
+		return fmt.Errorf("expected %d arguments, got %d", len(ptrs), len(vs))
+	}
+	for i, ptr := range ptrs {
+		rv := reflect.ValueOf(ptr)
+		if rv.Kind() != reflect.Ptr || rv.IsNil() {
+			return fmt.Errorf("cannot unmarshal into non-pointer %T", ptr)
+		}
+		if err := assign(rv.Elem(), vs[i], false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// structField is a field of a struct mapped to a record or variant field.
+type structField struct {
+	index int
+	name  string
+	nat   bool
+}
+
+// structFields returns the fields of the struct and whether it is a variant.
+func structFields(t reflect.Type) ([]structField, bool, error) {
+	var fields []structField
+	variant := false
+	names := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := strings.Split(f.Tag.Get("candid"), ",")
+		if f.Name == "_" {
+			for _, option := range tag[1:] {
+				if option == "variant" {
+					variant = true
+				}
+			}
+			continue
+		}
+		if f.PkgPath != "" || tag[0] == "-" {
+			continue
+		}
+		field := structField{index: i, name: f.Name}
+		if tag[0] != "" {
+			field.name = tag[0]
+		}
+		for _, option := range tag[1:] {
+			if option == "nat" {
+				field.nat = true
+			}
+		}
+		if names[field.name] {
+			return nil, false, fmt.Errorf("duplicate field %s in %s", field.name, t)
+		}
+		names[field.name] = true
+		fields = append(fields, field)
+	}
+	if variant {
+		for _, f := range fields {
+			if t.Field(f.index).Type.Kind() != reflect.Ptr {
+				return nil, false, fmt.Errorf("variant field %s of %s must be a pointer", f.name, t)
+			}
+		}
+	}
+	return fields, variant, nil
+}
+
+// typeBuilder derives Candid types from Go types, tying recursive structs
+// with knots.
+type typeBuilder struct {
+	knots map[reflect.Type]*knotRef
+}
+
+type knotRef struct {
+	knot *Knot
+	used bool
+}
+
+func newTypeBuilder() *typeBuilder {
+	return &typeBuilder{knots: map[reflect.Type]*knotRef{}}
+}
+
+func (b *typeBuilder) typeOf(t reflect.Type, nat bool) (Type, error) {
+	switch t {
+	case bigIntGoType:
+		if nat {
+			return new(Nat), nil
+		}
+		return new(Int), nil
+	case principalGoType:
+		return new(Principal), nil
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return new(Bool), nil
+	case reflect.String:
+		return new(Text), nil
+	case reflect.Int8:
+		return Int8(), nil
+	case reflect.Int16:
+		return Int16(), nil
+	case reflect.Int32:
+		return Int32(), nil
+	case reflect.Int, reflect.Int64:
+		return Int64(), nil
+	case reflect.Uint8:
+		return Nat8(), nil
+	case reflect.Uint16:
+		return Nat16(), nil
+	case reflect.Uint32:
+		return Nat32(), nil
+	case reflect.Uint, reflect.Uint64:
+		return Nat64(), nil
+	case reflect.Float32:
+		return Float32(), nil
+	case reflect.Float64:
+		return Float64(), nil
+	case reflect.Ptr:
+		elem, err := b.typeOf(t.Elem(), nat)
+		if err != nil {
+			return nil, err
+		}
+		return NewOpt(elem), nil
+	case reflect.Slice, reflect.Array:
+		elem, err := b.typeOf(t.Elem(), nat)
+		if err != nil {
+			return nil, err
+		}
+		return NewVec(elem), nil
+	case reflect.Struct:
+		return b.structType(t)
+	}
+	return nil, fmt.Errorf("unsupported type: %s", t)
+}
+
+func (b *typeBuilder) structType(t reflect.Type) (Type, error) {
+	if ref, ok := b.knots[t]; ok {
+		ref.used = true
+		return ref.knot, nil
+	}
+	ref := &knotRef{knot: NewKnot(t.String())}
+	b.knots[t] = ref
+	defer delete(b.knots, t)
+
+	fields, variant, err := structFields(t)
+	if err != nil {
+		return nil, err
+	}
+	types := map[string]Type{}
+	for _, f := range fields {
+		ft := t.Field(f.index).Type
+		if variant {
+			ft = ft.Elem()
+		}
+		if variant && ft == emptyStructGoType {
+			types[f.name] = new(Null)
+			continue
+		}
+		ftype, err := b.typeOf(ft, f.nat)
+		if err != nil {
+			return nil, err
+		}
+		types[f.name] = ftype
+	}
+	var st Type = NewRec(types)
+	if variant {
+		st = NewVariant(types)
+	}
+	if !ref.used {
+		return st, nil
+	}
+	ref.knot.Fill(st)
+	return ref.knot, nil
+}
+
+// valueOf converts a Go value to the value expected by the Candid type of
+// its Go type.
+func valueOf(v reflect.Value, nat bool) (interface{}, error) {
+	switch v.Type() {
+	case bigIntGoType:
+		bi := new(big.Int)
+		if v.CanAddr() {
+			bi.Set(v.Addr().Interface().(*big.Int))
+		} else {
+			i := v.Interface().(big.Int)
+			bi.Set(&i)
+		}
+		if nat && bi.Sign() < 0 {
+			return nil, fmt.Errorf("invalid nat: %s", bi)
+		}
+		return bi, nil
+	case principalGoType:
+		return icprincipal.Principal(v.Interface().(principal.Principal).Bytes), nil
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return big.NewInt(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return new(big.Int).SetUint64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return big.NewFloat(v.Float()), nil
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return valueOf(v.Elem(), nat)
+	case reflect.Slice, reflect.Array:
+		vs := make([]interface{}, v.Len())
+		for i := range vs {
+			e, err := valueOf(v.Index(i), nat)
+			if err != nil {
+				return nil, err
+			}
+			vs[i] = e
+		}
+		return vs, nil
+	case reflect.Struct:
+		fields, variant, err := structFields(v.Type())
+		if err != nil {
+			return nil, err
+		}
+		if variant {
+			return variantValueOf(v, fields)
+		}
+		rec := make(map[string]interface{})
+		for _, f := range fields {
+			fv, err := valueOf(v.Field(f.index), f.nat)
+			if err != nil {
+				return nil, err
+			}
+			rec[f.name] = fv
+		}
+		return rec, nil
+	}
+	return nil, fmt.Errorf("unsupported type: %s", v.Type())
+}
+
+func variantValueOf(v reflect.Value, fields []structField) (interface{}, error) {
+	var value *FieldValue
+	for _, f := range fields {
+		fv := v.Field(f.index)
+		if fv.IsNil() {
+			continue
+		}
+		if value != nil {
+			return nil, fmt.Errorf("more than one field of variant %s is set", v.Type())
+		}
+		value = &FieldValue{Name: f.name}
+		if fv.Type().Elem() != emptyStructGoType {
+			e, err := valueOf(fv.Elem(), f.nat)
+			if err != nil {
+				return nil, err
+			}
+			value.Value = e
+		}
+	}
+	if value == nil {
+		return nil, fmt.Errorf("no field of variant %s is set", v.Type())
+	}
+	return *value, nil
+}
+
+// matchField reports whether the label of a decoded record or variant field,
+// which is its hash when the name is unknown to the decoder, is name.
+func matchField(name string, fieldName string) bool {
+	return fieldName == name || fieldName == LabelId(name).String()
+}
+
+// assign sets dst to the decoded value v.
+func assign(dst reflect.Value, v interface{}, nat bool) error {
+	if dst.Kind() == reflect.Interface && dst.NumMethod() == 0 {
+		if v != nil {
+			dst.Set(reflect.ValueOf(v))
+		}
+		return nil
+	}
+	invalid := func() error {
+		return fmt.Errorf("cannot unmarshal %T into %s", v, dst.Type())
+	}
+	switch dst.Type() {
+	case bigIntGoType:
+		bi, ok := v.(*big.Int)
+		if !ok {
+			return invalid()
+		}
+		dst.Addr().Interface().(*big.Int).Set(bi)
+		return nil
+	case principalGoType:
+		p, ok := v.(icprincipal.Principal)
+		if !ok {
+			return invalid()
+		}
+		dst.Set(reflect.ValueOf(principal.Principal{Bytes: []byte(p)}))
+		return nil
+	}
+	switch dst.Kind() {
+	case reflect.Bool:
+		b, ok := v.(bool)
+		if !ok {
+			return invalid()
+		}
+		dst.SetBool(b)
+	case reflect.String:
+		s, ok := v.(string)
+		if !ok {
+			return invalid()
+		}
+		dst.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		bi, ok := v.(*big.Int)
+		if !ok || !bi.IsInt64() || dst.OverflowInt(bi.Int64()) {
+			return invalid()
+		}
+		dst.SetInt(bi.Int64())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		bi, ok := v.(*big.Int)
+		if !ok || !bi.IsUint64() || dst.OverflowUint(bi.Uint64()) {
+			return invalid()
+		}
+		dst.SetUint(bi.Uint64())
+	case reflect.Float32, reflect.Float64:
+		bf, ok := v.(*big.Float)
+		if !ok {
+			return invalid()
+		}
+		f, _ := bf.Float64()
+		dst.SetFloat(f)
+	case reflect.Ptr:
+		if v == nil {
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		elem := reflect.New(dst.Type().Elem())
+		if err := assign(elem.Elem(), v, nat); err != nil {
+			return err
+		}
+		dst.Set(elem)
+	case reflect.Slice:
+		vs, ok := v.([]interface{})
+		if !ok && v != nil {
+			return invalid()
+		}
+		s := reflect.MakeSlice(dst.Type(), len(vs), len(vs))
+		for i, e := range vs {
+			if err := assign(s.Index(i), e, nat); err != nil {
+				return err
+			}
+		}
+		dst.Set(s)
+	case reflect.Array:
+		vs, ok := v.([]interface{})
+		if !ok && v != nil || len(vs) != dst.Len() {
+			return invalid()
+		}
+		for i, e := range vs {
+			if err := assign(dst.Index(i), e, nat); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		fields, variant, err := structFields(dst.Type())
+		if err != nil {
+			return err
+		}
+		if variant {
+			return assignVariant(dst, fields, v)
+		}
+		rec, ok := v.(map[string]interface{})
+		if !ok && v != nil {
+			return invalid()
+		}
+	fields:
+		for _, f := range fields {
+			for name, fv := range rec {
+				if matchField(f.name, name) {
+					if err := assign(dst.Field(f.index), fv, f.nat); err != nil {
+						return err
+					}
+					continue fields
+				}
+			}
+			if dst.Field(f.index).Kind() != reflect.Ptr {
+				return fmt.Errorf("missing field %s of %s", f.name, dst.Type())
+			}
+			dst.Field(f.index).Set(reflect.Zero(dst.Field(f.index).Type()))
+		}
+	default:
+		return fmt.Errorf("unsupported type: %s", dst.Type())
+	}
+	return nil
+}
+
+func assignVariant(dst reflect.Value, fields []structField, v interface{}) error {
+	fv, ok := v.(*FieldValue)
+	if !ok {
+		return fmt.Errorf("cannot unmarshal %T into %s", v, dst.Type())
+	}
+	for _, f := range fields {
+		dst.Field(f.index).Set(reflect.Zero(dst.Field(f.index).Type()))
+	}
+	for _, f := range fields {
+		if !matchField(f.name, fv.Name) {
+			continue
+		}
+		field := dst.Field(f.index)
+		elem := reflect.New(field.Type().Elem())
+		if field.Type().Elem() != emptyStructGoType {
+			if err := assign(elem.Elem(), fv.Value, f.nat); err != nil {
+				return err
+			}
+		}
+		field.Set(elem)
+		return nil
+	}
+	return fmt.Errorf("unknown variant %s of %s", fv.Name, dst.Type())
+}
@@ -0,0 +1,122 @@
+package idl_test
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/icpfans-xyz/agent-go/candid/idl"
+	"github.com/icpfans-xyz/agent-go/principal"
+)
+
+type transfer struct {
+	To     principal.Principal `candid:"to"`
+	Amount big.Int             `candid:"amount,nat"`
+	Memo   *uint64             `candid:"memo"`
+	Data   []byte              `candid:"data"`
+	Note   string              `candid:"-"`
+}
+
+type transferResult struct {
+	_   struct{}  `candid:",variant"`
+	Ok  *uint64   `candid:"Ok"`
+	Err *struct{} `candid:"Err"`
+}
+
+type tree struct {
+	Value    int32  `candid:"value"`
+	Children []tree `candid:"children"`
+}
+
+func ExampleMarshal() {
+	memo := uint64(7)
+	bs, err := idl.Marshal(transfer{
+		To:     *principal.Anonymous(),
+		Amount: *big.NewInt(1000),
+		Memo:   &memo,
+		Data:   []byte{1, 2},
+	}, int8(-1))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	ts, _, _ := idl.Decode(bs)
+	fmt.Println(ts)
+
+	var t transfer
+	var i int8
+	if err := idl.Unmarshal(bs, &t, &i); err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(t.To.String(), t.Amount.String(), *t.Memo, t.Data, i)
+	// Output:
+	// [record {25979:principal; 1113806378:vec nat8; 1213809850:opt nat64; 3573748184:nat} int8]
+	// 2vxsx-fae 1000 7 [1 2] -1
+}
+
+func ExampleMarshal_variant() {
+	height := uint64(42)
+	for _, r := range []transferResult{{Ok: &height}, {Err: &struct{}{}}} {
+		bs, err := idl.Marshal(r)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		var result transferResult
+		if err := idl.Unmarshal(bs, &result); err != nil {
+			fmt.Println(err)
+			return
+		}
+		if result.Ok != nil {
+			fmt.Println("Ok", *result.Ok)
+		} else {
+			fmt.Println("Err", result.Err != nil)
+		}
+	}
+	_, err := idl.Marshal(transferResult{})
+	fmt.Println(err)
+	// Output:
+	// Ok 42
+	// Err true
+	// no field of variant idl_test.transferResult is set
+}
+
+func ExampleMarshal_recursive() {
+	bs, err := idl.Marshal(tree{Value: 1, Children: []tree{{Value: 2}, {Value: 3}}})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	ts, _, _ := idl.Decode(bs)
+	fmt.Println(ts)
+	var t tree
+	if err := idl.Unmarshal(bs, &t); err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(t.Value, len(t.Children), t.Children[1].Value)
+	// Output:
+	// [record {834174833:int32; 1886001471:vec table0}]
+	// 1 2 3
+}
+
+func ExampleUnmarshal() {
+	bs, _ := idl.Encode([]idl.Type{new(idl.Nat)}, []interface{}{big.NewInt(256)})
+	var small uint8
+	fmt.Println(idl.Unmarshal(bs, &small))
+	var large uint16
+	fmt.Println(idl.Unmarshal(bs, &large), large)
+	// Output:
+	// cannot unmarshal *big.Int into uint8
+	// <nil> 256
+}
+
+func ExampleMarshalAs() {
+	bs, err := idl.MarshalAs([]idl.Type{new(idl.Nat), idl.NewOpt(new(idl.Text))}, *big.NewInt(5), nil)
+	fmt.Printf("%x %v\n", bs, err)
+	_, err = idl.MarshalAs([]idl.Type{new(idl.Nat)}, *big.NewInt(-5))
+	fmt.Println(err)
+	// Output:
+	// 4449444c016e71027d000500 <nil>
+	// invalid nat: -5
+}
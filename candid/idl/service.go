@@ -30,11 +30,16 @@ func NewService(methods map[string]*Func) *Service {
 		})
 	}
 	sort.Slice(service.methods, func(i, j int) bool {
-		return Hash(service.methods[i].Name).Cmp(Hash(service.methods[j].Name)) < 0
+		return service.methods[i].Name < service.methods[j].Name
 	})
 	return &service
 }
 
+// Methods returns the methods of the service, sorted by name.
+func (s Service) Methods() []Method {
+	return s.methods
+}
+
 func (s Service) AddTypeDefinition(tdt *TypeDefinitionTable) error {
 	for _, f := range s.methods {
 		if err := f.Func.AddTypeDefinition(tdt); err != nil {
@@ -76,16 +81,19 @@ func (s Service) Decode(r *bytes.Reader) (interface{}, error) {
 			return nil, err
 		}
 		if n != 1 || bs[0] != 0x01 {
-			return nil, fmt.Errorf("invalid func reference: %d", bs)
+			return nil, fmt.Errorf("invalid service reference: %d", bs)
 		}
 	}
 	l, err := leb128.DecodeUnsigned(r)
 	if err != nil {
 		return nil, err
 	}
+	if !l.IsInt64() || l.Int64() > int64(r.Len()) {
+		return nil, fmt.Errorf("invalid principal id length: %s", l)
+	}
 	pid := make(principal.Principal, l.Int64())
 	n, err := r.Read(pid)
-	if err != nil {
+	if err != nil && len(pid) != 0 {
 		return nil, err
 	}
 	if n != int(l.Int64()) {
@@ -0,0 +1,344 @@
+package idl
+
+import (
+	"fmt"
+)
+
+// DecodeAs decodes the arguments and coerces them to the expected types,
+// following the subtyping rules of Candid: see
+// https://github.com/dfinity/candid/blob/master/spec/Candid.md#coercion.
+// This allows to decode the replies of a canister whose interface evolved,
+// for example by adding record fields or opt arguments:
+//
+//   - record fields that are not expected are skipped;
+//   - missing record fields and arguments of type opt, null or reserved are
+//     null;
+//   - nat values are accepted for int;
+//   - any value is accepted for reserved, and decodes to nil;
+//   - func and service references are accepted if their wire type is a
+//     subtype of the expected type: methods can be added, and the arguments
+//     of a function are contravariant and its results covariant;
+//   - an opt value that cannot be coerced is null, as well as a value of
+//     type null or reserved, and any other value is coerced to the type of
+//     the option.
+//
+// Record and variant fields of the result are named after the expected types.
+//
+// The message is decoded within the limits of DefaultDecoderConfig.
+func DecodeAs(bs []byte, expected []Type) ([]interface{}, error) {
+	return DefaultDecoderConfig.DecodeAs(bs, expected)
+}
+
+// DecodeAs decodes the arguments like DecodeAs, within the limits.
+func (c DecoderConfig) DecodeAs(bs []byte, expected []Type) ([]interface{}, error) {
+	ts, vs, err := c.Decode(bs)
+	if err != nil {
+		return nil, err
+	}
+	var values []interface{}
+	for i, e := range expected {
+		if i >= len(ts) {
+			if !isOptional(e) {
+				return nil, &DecodeError{
+					Types:       ts,
+					Description: fmt.Sprintf("missing argument of type %s", e),
+				}
+			}
+			values = append(values, nil)
+			continue
+		}
+		v, err := coerce(ts[i], e, vs[i])
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// resolve follows knots to the type they refer to.
+func resolve(t Type) Type {
+	for {
+		k, ok := t.(*Knot)
+		if !ok || k.t == nil {
+			return t
+		}
+		t = k.t
+	}
+}
+
+// isOptional reports whether null is a subtype of the type, so that a missing
+// value of the type can be taken as null.
+func isOptional(t Type) bool {
+	switch resolve(t).(type) {
+	case *Opt, *Null, *Reserved:
+		return true
+	}
+	return false
+}
+
+func subtypeError(wire, expected Type) error {
+	return &DecodeError{
+		Types:       Tuple{wire},
+		Description: fmt.Sprintf("is not a subtype of %s", expected),
+	}
+}
+
+// coerce converts the value v of the wire type to the expected type.
+func coerce(wire, expected Type, v interface{}) (interface{}, error) {
+	w, e := resolve(wire), resolve(expected)
+	switch e := e.(type) {
+	case *Reserved:
+		return nil, nil
+	case *Opt:
+		switch w := w.(type) {
+		case *Null, *Reserved:
+			return nil, nil
+		case *Opt:
+			if v == nil {
+				return nil, nil
+			}
+			v, err := coerce(w.Type, e.Type, v)
+			if err != nil {
+				return nil, nil
+			}
+			return v, nil
+		}
+		if isOptional(e.Type) {
+			return nil, nil
+		}
+		v, err := coerce(w, e.Type, v)
+		if err != nil {
+			return nil, nil
+		}
+		return v, nil
+	case *Vec:
+		w, ok := w.(*Vec)
+		if !ok {
+			return nil, subtypeError(wire, expected)
+		}
+		vs, _ := v.([]interface{})
+		var values []interface{}
+		for _, v := range vs {
+			v, err := coerce(w.Type, e.Type, v)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		return values, nil
+	case *Rec:
+		w, ok := w.(*Rec)
+		if !ok {
+			return nil, subtypeError(wire, expected)
+		}
+		if err := checkLabels(e.Fields); err != nil {
+			return nil, err
+		}
+		fields, _ := v.(map[string]interface{})
+		rec := make(map[string]interface{})
+	fields:
+		for _, f := range e.Fields {
+			for _, wf := range w.Fields {
+				if matchField(f.Name, wf.Name) {
+					v, err := coerce(wf.Type, f.Type, fields[wf.Name])
+					if err != nil {
+						return nil, err
+					}
+					rec[f.Name] = v
+					continue fields
+				}
+			}
+			if !isOptional(f.Type) {
+				return nil, &DecodeError{
+					Types:       Tuple{wire},
+					Description: fmt.Sprintf("missing field %s", f.Name),
+				}
+			}
+			rec[f.Name] = nil
+		}
+		return rec, nil
+	case *Variant:
+		w, ok := w.(*Variant)
+		if !ok {
+			return nil, subtypeError(wire, expected)
+		}
+		if err := checkLabels(e.Fields); err != nil {
+			return nil, err
+		}
+		fv, ok := v.(*FieldValue)
+		if !ok {
+			return nil, fmt.Errorf("invalid variant value: %v", v)
+		}
+		var wt Type
+		for _, wf := range w.Fields {
+			if wf.Name == fv.Name {
+				wt = wf.Type
+			}
+		}
+		for _, f := range e.Fields {
+			if wt != nil && matchField(f.Name, fv.Name) {
+				v, err := coerce(wt, f.Type, fv.Value)
+				if err != nil {
+					return nil, err
+				}
+				return &FieldValue{
+					Name:  f.Name,
+					Value: v,
+				}, nil
+			}
+		}
+		return nil, &DecodeError{
+			Types:       Tuple{wire},
+			Description: fmt.Sprintf("unexpected variant field %s", fv.Name),
+		}
+	case *Func, *Service:
+		if !subtype(wire, expected, nil) {
+			return nil, subtypeError(wire, expected)
+		}
+		return v, nil
+	case *Int:
+		// nat <: int
+		if n, ok := w.(*Nat); ok && n.Base == 0 && e.Base == 0 {
+			return v, nil
+		}
+	}
+	if _, ok := w.(PrimType); ok && w.String() == e.String() {
+		return v, nil
+	}
+	return nil, subtypeError(wire, expected)
+}
+
+// subtype reports whether the type t is a subtype of the type u. Recursive
+// types are compared coinductively: the pairs of knots in assumed are taken
+// to be subtypes.
+func subtype(t, u Type, assumed map[[2]*Knot]bool) bool {
+	if tk, ok := t.(*Knot); ok {
+		if uk, ok := u.(*Knot); ok {
+			pair := [2]*Knot{tk, uk}
+			if assumed[pair] {
+				return true
+			}
+			if assumed == nil {
+				assumed = make(map[[2]*Knot]bool)
+			}
+			assumed[pair] = true
+		}
+	}
+	switch u := resolve(u).(type) {
+	case *Reserved, *Opt:
+		// Any value coerces to reserved, and to an option, if only to null.
+		return true
+	case *Vec:
+		t, ok := resolve(t).(*Vec)
+		return ok && subtype(t.Type, u.Type, assumed)
+	case *Rec:
+		t, ok := resolve(t).(*Rec)
+		if !ok {
+			return false
+		}
+	fields:
+		for _, f := range u.Fields {
+			for _, tf := range t.Fields {
+				if sameLabel(tf.Name, f.Name) {
+					if !subtype(tf.Type, f.Type, assumed) {
+						return false
+					}
+					continue fields
+				}
+			}
+			if !isOptional(f.Type) {
+				return false
+			}
+		}
+		return true
+	case *Variant:
+		t, ok := resolve(t).(*Variant)
+		if !ok {
+			return false
+		}
+	cases:
+		for _, tf := range t.Fields {
+			for _, f := range u.Fields {
+				if sameLabel(tf.Name, f.Name) {
+					if !subtype(tf.Type, f.Type, assumed) {
+						return false
+					}
+					continue cases
+				}
+			}
+			return false
+		}
+		return true
+	case *Func:
+		t, ok := resolve(t).(*Func)
+		return ok && subtypeFunc(t, u, assumed)
+	case *Service:
+		t, ok := resolve(t).(*Service)
+		if !ok {
+			return false
+		}
+	methods:
+		for _, m := range u.methods {
+			for _, tm := range t.methods {
+				if tm.Name == m.Name {
+					if !subtypeFunc(tm.Func, m.Func, assumed) {
+						return false
+					}
+					continue methods
+				}
+			}
+			return false
+		}
+		return true
+	case *Int:
+		// nat <: int
+		if n, ok := resolve(t).(*Nat); ok && n.Base == 0 && u.Base == 0 {
+			return true
+		}
+	}
+	t = resolve(t)
+	_, ok := t.(PrimType)
+	return ok && t.String() == u.String()
+}
+
+// subtypeFunc reports whether the function type t is a subtype of u: a
+// function of type t can be called with the arguments of u, and returns
+// results of u.
+func subtypeFunc(t, u *Func, assumed map[[2]*Knot]bool) bool {
+	if len(t.Annotations) != len(u.Annotations) {
+		return false
+	}
+	for _, a := range t.Annotations {
+		var found bool
+		for _, b := range u.Annotations {
+			found = found || a == b
+		}
+		if !found {
+			return false
+		}
+	}
+	for i, a := range t.ArgTypes {
+		if i >= len(u.ArgTypes) {
+			if !isOptional(a) {
+				return false
+			}
+			continue
+		}
+		if !subtype(u.ArgTypes[i], a, assumed) {
+			return false
+		}
+	}
+	for i, r := range u.RetTypes {
+		if i >= len(t.RetTypes) {
+			if !isOptional(r) {
+				return false
+			}
+			continue
+		}
+		if !subtype(t.RetTypes[i], r, assumed) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,138 @@
+package idl_test
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/icpfans-xyz/agent-go/candid/idl"
+	"github.com/mix-labs/IC-Go/utils/principal"
+)
+
+func ExampleDecodeAs() {
+	// A newer version of the canister added fields and an argument.
+	bs, _ := idl.Encode([]idl.Type{
+		idl.NewRec(map[string]idl.Type{
+			"name":    new(idl.Text),
+			"balance": new(idl.Nat),
+			"email":   new(idl.Text),
+		}),
+		new(idl.Bool),
+	}, []interface{}{
+		map[string]interface{}{
+			"name":    "alice",
+			"balance": big.NewInt(10),
+			"email":   "alice@example.com",
+		},
+		true,
+	})
+	vs, err := idl.DecodeAs(bs, []idl.Type{
+		idl.NewRec(map[string]idl.Type{
+			"name":    new(idl.Text),
+			"balance": new(idl.Int),
+			"age":     idl.NewOpt(new(idl.Nat)),
+		}),
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	rec := vs[0].(map[string]interface{})
+	fmt.Println(rec["name"], rec["balance"], rec["age"])
+	// Output:
+	// alice 10 <nil>
+}
+
+func ExampleDecodeAs_opt() {
+	bs, _ := idl.Encode([]idl.Type{
+		new(idl.Nat),
+		idl.NewOpt(new(idl.Text)),
+		new(idl.Null),
+	}, []interface{}{big.NewInt(1), "a", nil})
+	vs, err := idl.DecodeAs(bs, []idl.Type{
+		idl.NewOpt(new(idl.Nat)),
+		idl.NewOpt(new(idl.Nat)),
+		idl.NewOpt(new(idl.Text)),
+		idl.NewOpt(new(idl.Text)),
+	})
+	fmt.Println(vs, err)
+	// Output:
+	// [1 <nil> <nil> <nil>] <nil>
+}
+
+func ExampleDecodeAs_variant() {
+	wire := idl.NewVariant(map[string]idl.Type{
+		"ok":  new(idl.Nat),
+		"err": new(idl.Text),
+	})
+	bs, _ := idl.Encode([]idl.Type{wire}, []interface{}{
+		idl.FieldValue{Name: "ok", Value: big.NewInt(5)},
+	})
+	vs, err := idl.DecodeAs(bs, []idl.Type{idl.NewVariant(map[string]idl.Type{
+		"ok":      new(idl.Int),
+		"err":     new(idl.Reserved),
+		"pending": new(idl.Null),
+	})})
+	fmt.Println(show(vs), err)
+
+	_, err = idl.DecodeAs(bs, []idl.Type{idl.NewVariant(map[string]idl.Type{
+		"err": new(idl.Text),
+	})})
+	fmt.Println(err)
+
+	_, err = idl.DecodeAs(bs, []idl.Type{wire, new(idl.Text)})
+	fmt.Println(err)
+	// Output:
+	// [ok(5)] <nil>
+	// (variant {24860:nat; 5048165:text}) unexpected variant field 24860
+	// (variant {24860:nat; 5048165:text}) missing argument of type text
+}
+
+func ExampleDecodeAs_func() {
+	p, _ := principal.Decode("w7x7r-cok77-xa")
+	// The canister returns a function that takes an optional argument more
+	// and a record with a field more.
+	wire := idl.NewFunc(
+		[]idl.Type{new(idl.Int), idl.NewOpt(new(idl.Text))},
+		[]idl.Type{idl.NewRec(map[string]idl.Type{
+			"a": new(idl.Nat),
+			"b": new(idl.Text),
+		})},
+		[]string{"query"},
+	)
+	bs, _ := idl.Encode([]idl.Type{wire}, []interface{}{
+		idl.PrincipalMethod{Principal: p, Method: "get"},
+	})
+	for _, expected := range []*idl.Func{
+		idl.NewFunc(
+			[]idl.Type{new(idl.Nat)},
+			[]idl.Type{idl.NewRec(map[string]idl.Type{"a": new(idl.Int)})},
+			[]string{"query"},
+		),
+		// The function cannot be called with a text.
+		idl.NewFunc(
+			[]idl.Type{new(idl.Text)},
+			[]idl.Type{idl.NewRec(map[string]idl.Type{"a": new(idl.Nat)})},
+			[]string{"query"},
+		),
+		// The function does not return a record with a field c.
+		idl.NewFunc(
+			[]idl.Type{new(idl.Int), idl.NewOpt(new(idl.Text))},
+			[]idl.Type{idl.NewRec(map[string]idl.Type{"c": new(idl.Nat)})},
+			[]string{"query"},
+		),
+		// The function is a query.
+		idl.NewFunc(
+			[]idl.Type{new(idl.Int)},
+			[]idl.Type{idl.NewRec(map[string]idl.Type{"a": new(idl.Nat)})},
+			nil,
+		),
+	} {
+		_, err := idl.DecodeAs(bs, []idl.Type{expected})
+		fmt.Println(err)
+	}
+	// Output:
+	// <nil>
+	// ((int, opt text) -> (record {97:nat; 98:text}) query) is not a subtype of (text) -> (record {a:nat}) query
+	// ((int, opt text) -> (record {97:nat; 98:text}) query) is not a subtype of (int, opt text) -> (record {c:nat}) query
+	// ((int, opt text) -> (record {97:nat; 98:text}) query) is not a subtype of (int) -> (record {a:nat})
+}
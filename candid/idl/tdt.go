@@ -5,7 +5,12 @@ type TypeDefinitionTable struct {
 	Indexes map[string]int
 }
 
+// Add adds the encoded type to the table, unless the table already contains
+// it.
 func (tdt *TypeDefinitionTable) Add(t Type, bs []byte) {
+	if _, ok := tdt.Indexes[t.String()]; ok {
+		return
+	}
 	i := len(tdt.Types)
 	tdt.Indexes[t.String()] = i
 	tdt.Types = append(tdt.Types, bs)
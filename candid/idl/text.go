@@ -19,6 +19,11 @@ func (t *Text) Decode(r *bytes.Reader) (interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
+	if !n.IsInt64() || n.Int64() > int64(r.Len()) {
+		return nil, &FormatError{
+			Description: "text: too long",
+		}
+	}
 	bs := make([]byte, n.Int64())
 	i, err := r.Read(bs)
 	if err != nil {
@@ -3,7 +3,6 @@ package idl
 import (
 	"bytes"
 	"encoding/binary"
-	"fmt"
 	"io"
 	"math"
 	"math/big"
@@ -44,22 +43,22 @@ func readFloat(r *bytes.Reader, n int) (*big.Float, error) {
 	if i != n {
 		return nil, io.EOF
 	}
+	var f float64
 	switch n {
 	case 4:
-		return big.NewFloat(
-			float64(math.Float32frombits(
-				binary.LittleEndian.Uint32(bs),
-			)),
-		), nil
+		f = float64(math.Float32frombits(
+			binary.LittleEndian.Uint32(bs),
+		))
 	default:
-		f := math.Float64frombits(
+		f = math.Float64frombits(
 			binary.LittleEndian.Uint64(bs),
 		)
-		if math.IsNaN(f) {
-			return nil, fmt.Errorf("float: NaN")
-		}
-		return big.NewFloat(f), nil
 	}
+	// A big.Float cannot be NaN: such values are rejected.
+	if math.IsNaN(f) {
+		return nil, &FormatError{Description: "float: NaN is not supported"}
+	}
+	return big.NewFloat(f), nil
 }
 
 func readInt(r *bytes.Reader, n int) (*big.Int, error) {
@@ -0,0 +1,642 @@
+package idl
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/aviate-labs/leb128"
+	"github.com/icpfans-xyz/agent-go/principal"
+	icprincipal "github.com/mix-labs/IC-Go/utils/principal"
+)
+
+// Value is a typed Candid value, an alternative to the loose representation
+// of values taken by Encode and returned by Decode. There is a value type
+// for each Candid type, which keeps the type of the value:
+//
+//	null      *NullValue
+//	reserved  *ReservedValue
+//	bool      *BoolValue
+//	nat       *NatValue
+//	int       *IntValue
+//	float     *FloatValue
+//	text      *TextValue
+//	principal *PrincipalValue
+//	opt       *OptValue
+//	vec       *VecValue
+//	record    *RecValue
+//	variant   *VariantValue
+//	func      *FuncValue
+//	service   *ServiceValue
+//
+// Knots are resolved, so the type of a value is never a *Knot. Principals
+// are principal.Principal values, converted from and to the principals of
+// the loose representation by ValueOf and Interface.
+type Value interface {
+	// Type returns the type of the value.
+	Type() Type
+	// String prints the value in the textual format of Candid.
+	String() string
+	value()
+}
+
+type (
+	NullValue struct{}
+
+	ReservedValue struct{}
+
+	BoolValue struct {
+		Value bool
+	}
+
+	NatValue struct {
+		T     *Nat
+		Value *big.Int
+	}
+
+	IntValue struct {
+		T     *Int
+		Value *big.Int
+	}
+
+	FloatValue struct {
+		T     *Float
+		Value *big.Float
+	}
+
+	TextValue struct {
+		Value string
+	}
+
+	PrincipalValue struct {
+		Value principal.Principal
+	}
+
+	// OptValue is an optional value, with a nil Value if it is null.
+	OptValue struct {
+		T     *Opt
+		Value Value
+	}
+
+	VecValue struct {
+		T      *Vec
+		Values []Value
+	}
+
+	// RecValue is a record, with the values of the fields in the order of
+	// the fields of its type.
+	RecValue struct {
+		T      *Rec
+		Values []Value
+	}
+
+	// VariantValue is a variant, with the index of its field in the fields
+	// of its type.
+	VariantValue struct {
+		T     *Variant
+		Index int
+		Value Value
+	}
+
+	FuncValue struct {
+		T         *Func
+		Principal principal.Principal
+		Method    string
+	}
+
+	ServiceValue struct {
+		T         *Service
+		Principal principal.Principal
+	}
+)
+
+func (*NullValue) Type() Type      { return new(Null) }
+func (*ReservedValue) Type() Type  { return new(Reserved) }
+func (*BoolValue) Type() Type      { return new(Bool) }
+func (v *NatValue) Type() Type     { return v.T }
+func (v *IntValue) Type() Type     { return v.T }
+func (v *FloatValue) Type() Type   { return v.T }
+func (*TextValue) Type() Type      { return new(Text) }
+func (*PrincipalValue) Type() Type { return new(Principal) }
+func (v *OptValue) Type() Type     { return v.T }
+func (v *VecValue) Type() Type     { return v.T }
+func (v *RecValue) Type() Type     { return v.T }
+func (v *VariantValue) Type() Type { return v.T }
+func (v *FuncValue) Type() Type    { return v.T }
+func (v *ServiceValue) Type() Type { return v.T }
+
+func (v *NullValue) String() string      { return formatValue(v) }
+func (v *ReservedValue) String() string  { return formatValue(v) }
+func (v *BoolValue) String() string      { return formatValue(v) }
+func (v *NatValue) String() string       { return formatValue(v) }
+func (v *IntValue) String() string       { return formatValue(v) }
+func (v *FloatValue) String() string     { return formatValue(v) }
+func (v *TextValue) String() string      { return formatValue(v) }
+func (v *PrincipalValue) String() string { return formatValue(v) }
+func (v *OptValue) String() string       { return formatValue(v) }
+func (v *VecValue) String() string       { return formatValue(v) }
+func (v *RecValue) String() string       { return formatValue(v) }
+func (v *VariantValue) String() string   { return formatValue(v) }
+func (v *FuncValue) String() string      { return formatValue(v) }
+func (v *ServiceValue) String() string   { return formatValue(v) }
+
+func (*NullValue) value()      {}
+func (*ReservedValue) value()  {}
+func (*BoolValue) value()      {}
+func (*NatValue) value()       {}
+func (*IntValue) value()       {}
+func (*FloatValue) value()     {}
+func (*TextValue) value()      {}
+func (*PrincipalValue) value() {}
+func (*OptValue) value()       {}
+func (*VecValue) value()       {}
+func (*RecValue) value()       {}
+func (*VariantValue) value()   {}
+func (*FuncValue) value()      {}
+func (*ServiceValue) value()   {}
+
+// formatValue prints the value like FormatValue, following the nodes of
+// composite values so that options within options are kept.
+func formatValue(v Value) string {
+	switch v := v.(type) {
+	case nil:
+		return "<nil>"
+	case *OptValue:
+		if v.Value == nil {
+			return "null"
+		}
+		return "opt " + formatValue(v.Value)
+	case *VecValue:
+		if n, ok := resolve(v.T.Type).(*Nat); ok && n.Base == 8 {
+			break
+		}
+		var s []string
+		for _, e := range v.Values {
+			s = append(s, formatValue(e))
+		}
+		return formatFields("vec", s)
+	case *RecValue:
+		tuple := isTuple(v.T)
+		var s []string
+		for i, e := range v.Values {
+			f := formatValue(e)
+			if !tuple && i < len(v.T.Fields) {
+				f = fmt.Sprintf("%s = %s", formatLabel(v.T.Fields[i].Name), f)
+			}
+			s = append(s, f)
+		}
+		return formatFields("record", s)
+	case *VariantValue:
+		if v.Index < 0 || v.Index >= len(v.T.Fields) {
+			break
+		}
+		name := formatLabel(v.Name())
+		if _, ok := v.Value.(*NullValue); ok {
+			return fmt.Sprintf("variant { %s }", name)
+		}
+		return fmt.Sprintf("variant { %s = %s }", name, formatValue(v.Value))
+	}
+	s, err := FormatValue(v.Type(), Interface(v))
+	if err != nil {
+		return fmt.Sprintf("<invalid %s: %v>", v.Type(), err)
+	}
+	return s
+}
+
+// Field returns the value of the field with the given name or id.
+func (v *RecValue) Field(name string) (Value, bool) {
+	for i, f := range v.T.Fields {
+		if sameLabel(f.Name, name) {
+			return v.Values[i], true
+		}
+	}
+	return nil, false
+}
+
+// Name returns the name of the field of the variant.
+func (v *VariantValue) Name() string {
+	return v.T.Fields[v.Index].Name
+}
+
+// ValueOf converts a value in the loose representation, like one returned by
+// Decode or taken by Encode, to a value of the type.
+func ValueOf(t Type, v interface{}) (Value, error) {
+	switch t := resolve(t).(type) {
+	case *Null:
+		return new(NullValue), nil
+	case *Reserved:
+		return new(ReservedValue), nil
+	case *Bool:
+		b, ok := v.(bool)
+		if !ok {
+			return nil, invalidValue(t, v)
+		}
+		return &BoolValue{Value: b}, nil
+	case *Nat:
+		n, ok := v.(*big.Int)
+		if !ok {
+			return nil, invalidValue(t, v)
+		}
+		if err := checkRange(t, n); err != nil {
+			return nil, err
+		}
+		return &NatValue{T: t, Value: n}, nil
+	case *Int:
+		n, ok := v.(*big.Int)
+		if !ok {
+			return nil, invalidValue(t, v)
+		}
+		if err := checkRange(t, n); err != nil {
+			return nil, err
+		}
+		return &IntValue{T: t, Value: n}, nil
+	case *Float:
+		f, ok := v.(*big.Float)
+		if !ok {
+			return nil, invalidValue(t, v)
+		}
+		return &FloatValue{T: t, Value: f}, nil
+	case *Text:
+		s, ok := v.(string)
+		if !ok {
+			return nil, invalidValue(t, v)
+		}
+		return &TextValue{Value: s}, nil
+	case *Principal:
+		p, ok := v.(icprincipal.Principal)
+		if !ok {
+			return nil, invalidValue(t, v)
+		}
+		return &PrincipalValue{Value: principal.Principal{Bytes: []byte(p)}}, nil
+	case *Service:
+		p, ok := v.(icprincipal.Principal)
+		if !ok {
+			return nil, invalidValue(t, v)
+		}
+		return &ServiceValue{T: t, Principal: principal.Principal{Bytes: []byte(p)}}, nil
+	case *Func:
+		pm, ok := v.(PrincipalMethod)
+		if !ok {
+			return nil, invalidValue(t, v)
+		}
+		return &FuncValue{T: t, Principal: principal.Principal{Bytes: []byte(pm.Principal)}, Method: pm.Method}, nil
+	case *Opt:
+		if v == nil {
+			return &OptValue{T: t}, nil
+		}
+		e, err := ValueOf(t.Type, v)
+		if err != nil {
+			return nil, err
+		}
+		return &OptValue{T: t, Value: e}, nil
+	case *Vec:
+		vs, ok := v.([]interface{})
+		if !ok && v != nil {
+			return nil, invalidValue(t, v)
+		}
+		vec := &VecValue{T: t, Values: make([]Value, len(vs))}
+		for i, v := range vs {
+			e, err := ValueOf(t.Type, v)
+			if err != nil {
+				return nil, err
+			}
+			vec.Values[i] = e
+		}
+		return vec, nil
+	case *Rec:
+		// Empty records are decoded as nil.
+		fields, ok := v.(map[string]interface{})
+		if !ok && v != nil {
+			return nil, invalidValue(t, v)
+		}
+		rec := &RecValue{T: t, Values: make([]Value, len(t.Fields))}
+		for i, f := range t.Fields {
+			e, err := ValueOf(f.Type, recordField(fields, f.Name))
+			if err != nil {
+				return nil, err
+			}
+			rec.Values[i] = e
+		}
+		return rec, nil
+	case *Variant:
+		var fv FieldValue
+		switch v := v.(type) {
+		case FieldValue:
+			fv = v
+		case *FieldValue:
+			fv = *v
+		default:
+			return nil, invalidValue(t, v)
+		}
+		for i, f := range t.Fields {
+			if !sameLabel(f.Name, fv.Name) {
+				continue
+			}
+			e, err := ValueOf(f.Type, fv.Value)
+			if err != nil {
+				return nil, err
+			}
+			return &VariantValue{T: t, Index: i, Value: e}, nil
+		}
+		return nil, fmt.Errorf("unknown variant field %s of %s", fv.Name, t)
+	}
+	return nil, fmt.Errorf("no values of type %s", t)
+}
+
+// Interface converts a value to the loose representation taken by Encode.
+// The loose representation of an option is nil or the value, so opt null and
+// opt opt null are converted to nil like null.
+func Interface(v Value) interface{} {
+	switch v := v.(type) {
+	case *BoolValue:
+		return v.Value
+	case *NatValue:
+		return v.Value
+	case *IntValue:
+		return v.Value
+	case *FloatValue:
+		return v.Value
+	case *TextValue:
+		return v.Value
+	case *PrincipalValue:
+		return icprincipal.Principal(v.Value.Bytes)
+	case *ServiceValue:
+		return icprincipal.Principal(v.Principal.Bytes)
+	case *FuncValue:
+		return PrincipalMethod{Principal: icprincipal.Principal(v.Principal.Bytes), Method: v.Method}
+	case *OptValue:
+		if v.Value == nil {
+			return nil
+		}
+		return Interface(v.Value)
+	case *VecValue:
+		var vs []interface{}
+		for _, e := range v.Values {
+			vs = append(vs, Interface(e))
+		}
+		return vs
+	case *RecValue:
+		rec := make(map[string]interface{})
+		for i, f := range v.T.Fields {
+			rec[f.Name] = Interface(v.Values[i])
+		}
+		return rec
+	case *VariantValue:
+		return FieldValue{Name: v.Name(), Value: Interface(v.Value)}
+	}
+	return nil
+}
+
+// DecodeValues decodes the arguments like Decode, as typed values. Unlike
+// the loose representation, the values keep options within options, like
+// opt null and opt opt null.
+func DecodeValues(bs []byte) ([]Value, error) {
+	types, r, err := decodeTypes(bs, nil, DefaultDecoderConfig)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]Value, len(types))
+	for i, t := range types {
+		v, err := decodeValue(t, r)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("too long")
+	}
+	return values, nil
+}
+
+func decodeValue(t Type, r *bytes.Reader) (Value, error) {
+	switch t := resolve(t).(type) {
+	case *Opt:
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		switch b {
+		case 0x00:
+			return &OptValue{T: t}, nil
+		case 0x01:
+			e, err := decodeValue(t.Type, r)
+			if err != nil {
+				return nil, err
+			}
+			return &OptValue{T: t, Value: e}, nil
+		}
+		return nil, fmt.Errorf("invalid option value")
+	case *Vec:
+		n, err := leb128.DecodeUnsigned(r)
+		if err != nil {
+			return nil, err
+		}
+		// The length is within the limits checked by decodeTypes.
+		vec := &VecValue{T: t, Values: make([]Value, n.Int64())}
+		for i := range vec.Values {
+			e, err := decodeValue(t.Type, r)
+			if err != nil {
+				return nil, err
+			}
+			vec.Values[i] = e
+		}
+		return vec, nil
+	case *Rec:
+		rec := &RecValue{T: t, Values: make([]Value, len(t.Fields))}
+		for i, f := range t.Fields {
+			e, err := decodeValue(f.Type, r)
+			if err != nil {
+				return nil, err
+			}
+			rec.Values[i] = e
+		}
+		return rec, nil
+	case *Variant:
+		i, err := leb128.DecodeUnsigned(r)
+		if err != nil {
+			return nil, err
+		}
+		if !i.IsInt64() || i.Int64() >= int64(len(t.Fields)) {
+			return nil, fmt.Errorf("invalid variant index: %v", i)
+		}
+		e, err := decodeValue(t.Fields[i.Int64()].Type, r)
+		if err != nil {
+			return nil, err
+		}
+		return &VariantValue{T: t, Index: int(i.Int64()), Value: e}, nil
+	default:
+		v, err := t.Decode(r)
+		if err != nil {
+			return nil, err
+		}
+		return ValueOf(t, v)
+	}
+}
+
+// EncodeValues encodes the values like Encode.
+func EncodeValues(values ...Value) ([]byte, error) {
+	types := make([]Type, len(values))
+	for i, v := range values {
+		types[i] = v.Type()
+	}
+	return encode(types, func(i int) ([]byte, error) {
+		return encodeValue(values[i])
+	})
+}
+
+func encodeValue(v Value) ([]byte, error) {
+	switch v := v.(type) {
+	case *OptValue:
+		if v.Value == nil {
+			return []byte{0x00}, nil
+		}
+		e, err := encodeValue(v.Value)
+		if err != nil {
+			return nil, err
+		}
+		return concat([]byte{0x01}, e), nil
+	case *VecValue:
+		bs, err := leb128.EncodeUnsigned(big.NewInt(int64(len(v.Values))))
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range v.Values {
+			e, err := encodeValue(e)
+			if err != nil {
+				return nil, err
+			}
+			bs = append(bs, e...)
+		}
+		return bs, nil
+	case *RecValue:
+		if len(v.Values) != len(v.T.Fields) {
+			return nil, fmt.Errorf("invalid number of fields of %s: %d", v.T, len(v.Values))
+		}
+		var bs []byte
+		for _, e := range v.Values {
+			e, err := encodeValue(e)
+			if err != nil {
+				return nil, err
+			}
+			bs = append(bs, e...)
+		}
+		return bs, nil
+	case *VariantValue:
+		if v.Index < 0 || v.Index >= len(v.T.Fields) {
+			return nil, fmt.Errorf("invalid variant index of %s: %d", v.T, v.Index)
+		}
+		bs, err := leb128.EncodeUnsigned(big.NewInt(int64(v.Index)))
+		if err != nil {
+			return nil, err
+		}
+		e, err := encodeValue(v.Value)
+		if err != nil {
+			return nil, err
+		}
+		return concat(bs, e), nil
+	}
+	return v.Type().EncodeValue(Interface(v))
+}
+
+// Equal reports whether the values are equal and of the same type.
+func Equal(a, b Value) bool {
+	if a.Type().String() != b.Type().String() {
+		return false
+	}
+	switch a := a.(type) {
+	case *NullValue, *ReservedValue:
+		return true
+	case *BoolValue:
+		return a.Value == b.(*BoolValue).Value
+	case *NatValue:
+		return a.Value.Cmp(b.(*NatValue).Value) == 0
+	case *IntValue:
+		return a.Value.Cmp(b.(*IntValue).Value) == 0
+	case *FloatValue:
+		return a.Value.Cmp(b.(*FloatValue).Value) == 0
+	case *TextValue:
+		return a.Value == b.(*TextValue).Value
+	case *PrincipalValue:
+		return bytes.Equal(a.Value.Bytes, b.(*PrincipalValue).Value.Bytes)
+	case *ServiceValue:
+		return bytes.Equal(a.Principal.Bytes, b.(*ServiceValue).Principal.Bytes)
+	case *FuncValue:
+		b := b.(*FuncValue)
+		return bytes.Equal(a.Principal.Bytes, b.Principal.Bytes) && a.Method == b.Method
+	case *OptValue:
+		b := b.(*OptValue)
+		if a.Value == nil || b.Value == nil {
+			return a.Value == nil && b.Value == nil
+		}
+		return Equal(a.Value, b.Value)
+	case *VecValue:
+		return equalValues(a.Values, b.(*VecValue).Values)
+	case *RecValue:
+		return equalValues(a.Values, b.(*RecValue).Values)
+	case *VariantValue:
+		b := b.(*VariantValue)
+		return a.Index == b.Index && Equal(a.Value, b.Value)
+	}
+	return false
+}
+
+func equalValues(a, b []Value) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// A Visitor's Visit method is called for each value encountered by Walk. If
+// the result visitor w is not nil, Walk visits each of the children of the
+// value with w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(v Value) (w Visitor)
+}
+
+// Walk traverses a value in depth-first order, like ast.Walk: the elements
+// of opts and vecs, the fields of records and the field of variants.
+func Walk(visitor Visitor, v Value) {
+	if visitor = visitor.Visit(v); visitor == nil {
+		return
+	}
+	switch v := v.(type) {
+	case *OptValue:
+		if v.Value != nil {
+			Walk(visitor, v.Value)
+		}
+	case *VecValue:
+		for _, e := range v.Values {
+			Walk(visitor, e)
+		}
+	case *RecValue:
+		for _, e := range v.Values {
+			Walk(visitor, e)
+		}
+	case *VariantValue:
+		Walk(visitor, v.Value)
+	}
+	visitor.Visit(nil)
+}
+
+type inspector func(Value) bool
+
+func (f inspector) Visit(v Value) Visitor {
+	if f(v) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses a value in depth-first order, calling f for each value
+// and then f(nil) after its children. The children are skipped if f returns
+// false.
+func Inspect(v Value, f func(Value) bool) {
+	Walk(inspector(f), v)
+}
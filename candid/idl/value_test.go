@@ -0,0 +1,107 @@
+package idl_test
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/icpfans-xyz/agent-go/candid/idl"
+	"github.com/icpfans-xyz/agent-go/principal"
+	icprincipal "github.com/mix-labs/IC-Go/utils/principal"
+)
+
+func ExampleDecodeValues() {
+	bs, _ := idl.Encode([]idl.Type{
+		idl.NewRec(map[string]idl.Type{
+			"owner": new(idl.Text),
+			"empty": idl.NewRec(nil),
+			"items": idl.NewVec(idl.NewVariant(map[string]idl.Type{
+				"A": idl.Nat8(),
+				"B": new(idl.Null),
+			})),
+		}),
+	}, []interface{}{
+		map[string]interface{}{
+			"owner": "alice",
+			"empty": map[string]interface{}{},
+			"items": []interface{}{
+				idl.FieldValue{Name: "A", Value: big.NewInt(1)},
+				idl.FieldValue{Name: "B"},
+			},
+		},
+	})
+	vs, err := idl.DecodeValues(bs)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	rec := vs[0].(*idl.RecValue)
+	owner, _ := rec.Field("owner")
+	fmt.Println(owner.(*idl.TextValue).Value)
+	// Empty records are values too.
+	empty, _ := rec.Field("empty")
+	fmt.Println(empty)
+	idl.Inspect(rec, func(v idl.Value) bool {
+		if v, ok := v.(*idl.VariantValue); ok {
+			fmt.Println(v.Name(), v.Value)
+		}
+		return true
+	})
+	// Output:
+	// alice
+	// record {}
+	// 65 1 : nat8
+	// 66 null
+}
+
+func ExampleEqual() {
+	t := idl.NewOpt(idl.NewVec(new(idl.Int)))
+	a, _ := idl.ValueOf(t, []interface{}{big.NewInt(1), big.NewInt(-1)})
+	b, _ := idl.ParseValue(t, "opt vec { 1; -1 }")
+	c, _ := idl.ValueOf(t, b)
+	fmt.Println(a, idl.Equal(a, c))
+	d, _ := idl.ValueOf(t, nil)
+	fmt.Println(d, idl.Equal(a, d))
+	// Values of different types are not equal.
+	e, _ := idl.ValueOf(idl.NewOpt(idl.NewVec(new(idl.Nat))), []interface{}{big.NewInt(1)})
+	fmt.Println(e, idl.Equal(a, e))
+	_, err := idl.ValueOf(idl.NewOpt(idl.NewVec(new(idl.Nat))), b)
+	fmt.Println(err)
+
+	bs, _ := idl.EncodeValues(a, d)
+	vs, _ := idl.DecodeValues(bs)
+	fmt.Println(idl.Equal(vs[0], a), idl.Equal(vs[1], d))
+	// Output:
+	// opt vec { 1; -1 } true
+	// null false
+	// opt vec { 1 } false
+	// -1 is out of range for nat
+	// true true
+}
+
+func ExampleEncodeValues() {
+	optNull := idl.NewOpt(new(idl.Null))
+	optOpt := idl.NewOpt(idl.NewOpt(new(idl.Nat)))
+	bs, _ := idl.EncodeValues(
+		&idl.OptValue{T: optNull},
+		&idl.OptValue{T: optNull, Value: new(idl.NullValue)},
+		&idl.OptValue{T: optOpt, Value: &idl.OptValue{T: optOpt.Type.(*idl.Opt)}},
+	)
+	fmt.Printf("%x\n", bs)
+	vs, _ := idl.DecodeValues(bs)
+	fmt.Println(vs)
+	// Output:
+	// 4449444c036e7f6e7d6e010300000200010100
+	// [null opt null opt null]
+}
+
+func ExampleValueOf_principal() {
+	p, _ := principal.FromString("w7x7r-cok77-xa")
+	v, _ := idl.ValueOf(new(idl.Principal), icprincipal.Principal(p.Bytes))
+	fmt.Println(v.(*idl.PrincipalValue).Value.ToString())
+	fmt.Println(idl.Interface(&idl.PrincipalValue{Value: *p}).(icprincipal.Principal).Encode())
+	fmt.Println(v)
+	// Output:
+	// w7x7r-cok77-xa
+	// w7x7r-cok77-xa
+	// principal "w7x7r-cok77-xa"
+}
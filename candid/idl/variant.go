@@ -28,12 +28,15 @@ func NewVariant(fields map[string]Type) *Variant {
 		})
 	}
 	sort.Slice(variant.Fields, func(i, j int) bool {
-		return Hash(variant.Fields[i].Name).Cmp(Hash(variant.Fields[j].Name)) < 0
+		return LabelId(variant.Fields[i].Name).Cmp(LabelId(variant.Fields[j].Name)) < 0
 	})
 	return &variant
 }
 
 func (v Variant) AddTypeDefinition(tdt *TypeDefinitionTable) error {
+	if err := checkLabels(v.Fields); err != nil {
+		return err
+	}
 	for _, f := range v.Fields {
 		if err := f.Type.AddTypeDefinition(tdt); err != nil {
 			return err
@@ -50,7 +53,7 @@ func (v Variant) AddTypeDefinition(tdt *TypeDefinitionTable) error {
 	}
 	var vs []byte
 	for _, f := range v.Fields {
-		id, err := leb128.EncodeUnsigned(Hash(f.Name))
+		id, err := leb128.EncodeUnsigned(LabelId(f.Name))
 		if err != nil {
 			return nil
 		}
@@ -0,0 +1,503 @@
+package candid
+
+import (
+	"math/big"
+	"unicode/utf8"
+
+	"github.com/icpfans-xyz/agent-go/candid/idl"
+)
+
+// The syntax tree of a .did file, which is turned into idl types once all
+// the definitions are known.
+type (
+	typeNode interface {
+		position() idl.Position
+	}
+
+	// refNode is a reference to a primitive type or to a definition.
+	refNode struct {
+		pos  idl.Position
+		name string
+	}
+
+	optNode struct {
+		pos  idl.Position
+		elem typeNode
+	}
+
+	vecNode struct {
+		pos  idl.Position
+		elem typeNode
+	}
+
+	recordNode struct {
+		pos    idl.Position
+		fields []fieldNode
+	}
+
+	variantNode struct {
+		pos    idl.Position
+		fields []fieldNode
+	}
+
+	funcNode struct {
+		pos         idl.Position
+		args        []typeNode
+		rets        []typeNode
+		annotations []string
+	}
+
+	serviceNode struct {
+		pos     idl.Position
+		methods []methodNode
+	}
+
+	// classNode is a service with init arguments, only allowed as the main
+	// service of a file.
+	classNode struct {
+		pos     idl.Position
+		args    []typeNode
+		service typeNode
+	}
+)
+
+type fieldNode struct {
+	pos   idl.Position
+	label string
+	// The type of the field, nil for variant fields of type null.
+	typ typeNode
+}
+
+type methodNode struct {
+	pos  idl.Position
+	name string
+	typ  typeNode
+}
+
+type definitionNode struct {
+	pos  idl.Position
+	name string
+	typ  typeNode
+	file string
+}
+
+type importNode struct {
+	pos  idl.Position
+	path string
+}
+
+// fileNode is a parsed .did file.
+type fileNode struct {
+	imports     []importNode
+	definitions []definitionNode
+	serviceName string
+	service     typeNode
+}
+
+func (n *refNode) position() idl.Position     { return n.pos }
+func (n *optNode) position() idl.Position     { return n.pos }
+func (n *vecNode) position() idl.Position     { return n.pos }
+func (n *recordNode) position() idl.Position  { return n.pos }
+func (n *variantNode) position() idl.Position { return n.pos }
+func (n *funcNode) position() idl.Position    { return n.pos }
+func (n *serviceNode) position() idl.Position { return n.pos }
+func (n *classNode) position() idl.Position   { return n.pos }
+
+// parser is a recursive descent parser of .did files, with one token of
+// lookahead.
+type parser struct {
+	file  string
+	lexer *idl.Lexer
+	tok   idl.Token
+}
+
+func parse(file, src string) (*fileNode, error) {
+	p := &parser{file: file, lexer: idl.NewLexer(src)}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	return p.parseFile()
+}
+
+func (p *parser) next() error {
+	tok, err := p.lexer.Next()
+	if err, ok := err.(*idl.SyntaxError); ok {
+		return parseError(p.file, err.Pos, "%s", err.Message)
+	} else if err != nil {
+		return err
+	}
+	// Escapes can produce any bytes, but .did files have no blobs.
+	if tok.Kind == idl.TokenText && !utf8.ValidString(tok.Value) {
+		return parseError(p.file, tok.Pos, "text is not valid UTF-8")
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) errorf(pos idl.Position, format string, args ...interface{}) error {
+	return parseError(p.file, pos, format, args...)
+}
+
+func (p *parser) unexpected(expected string) error {
+	return p.errorf(p.tok.Pos, "expected %s, found %s", expected, p.tok)
+}
+
+// is reports whether the current token is the symbol or keyword.
+func (p *parser) is(value string) bool {
+	return (p.tok.Kind == idl.TokenSymbol || p.tok.Kind == idl.TokenIdent) && p.tok.Value == value
+}
+
+// accept skips the current token if it is the symbol or keyword.
+func (p *parser) accept(value string) (bool, error) {
+	if !p.is(value) {
+		return false, nil
+	}
+	return true, p.next()
+}
+
+func (p *parser) expect(value string) error {
+	if !p.is(value) {
+		return p.unexpected(`"` + value + `"`)
+	}
+	return p.next()
+}
+
+func (p *parser) parseFile() (*fileNode, error) {
+	f := new(fileNode)
+	for p.tok.Kind != idl.TokenEOF {
+		switch {
+		case p.is("import"):
+			pos := p.tok.Pos
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			if p.is("service") {
+				return nil, p.errorf(p.tok.Pos, "service imports are not supported")
+			}
+			if p.tok.Kind != idl.TokenText {
+				return nil, p.unexpected("file name")
+			}
+			f.imports = append(f.imports, importNode{pos: pos, path: p.tok.Value})
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+		case p.is("type"):
+			pos := p.tok.Pos
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			if p.tok.Kind != idl.TokenIdent {
+				return nil, p.unexpected("type name")
+			}
+			name := p.tok.Value
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			if err := p.expect("="); err != nil {
+				return nil, err
+			}
+			t, err := p.parseType()
+			if err != nil {
+				return nil, err
+			}
+			f.definitions = append(f.definitions, definitionNode{
+				pos:  pos,
+				name: name,
+				typ:  t,
+				file: p.file,
+			})
+		case p.is("service"):
+			if f.service != nil {
+				return nil, p.errorf(p.tok.Pos, "duplicate service")
+			}
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			if p.tok.Kind == idl.TokenIdent {
+				f.serviceName = p.tok.Value
+				if err := p.next(); err != nil {
+					return nil, err
+				}
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			t, err := p.parseActor()
+			if err != nil {
+				return nil, err
+			}
+			f.service = t
+		default:
+			return nil, p.unexpected(`"type", "import" or "service"`)
+		}
+		// The semicolon is optional after the service, which comes last.
+		if f.service != nil && p.tok.Kind == idl.TokenEOF {
+			break
+		}
+		if err := p.expect(";"); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// parseActor parses the type of the main service, which can take init
+// arguments.
+func (p *parser) parseActor() (typeNode, error) {
+	pos := p.tok.Pos
+	if p.is("(") {
+		args, err := p.parseTuple()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("->"); err != nil {
+			return nil, err
+		}
+		service, err := p.parseServiceType()
+		if err != nil {
+			return nil, err
+		}
+		return &classNode{pos: pos, args: args, service: service}, nil
+	}
+	return p.parseServiceType()
+}
+
+// parseServiceType parses either the methods of a service or a reference to
+// a service type.
+func (p *parser) parseServiceType() (typeNode, error) {
+	if p.tok.Kind == idl.TokenIdent {
+		ref := &refNode{pos: p.tok.Pos, name: p.tok.Value}
+		return ref, p.next()
+	}
+	return p.parseMethods(p.tok.Pos)
+}
+
+func (p *parser) parseType() (typeNode, error) {
+	pos := p.tok.Pos
+	if p.tok.Kind != idl.TokenIdent {
+		return nil, p.unexpected("type")
+	}
+	name := p.tok.Value
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	switch name {
+	case "opt":
+		t, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		return &optNode{pos: pos, elem: t}, nil
+	case "vec":
+		t, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		return &vecNode{pos: pos, elem: t}, nil
+	case "blob":
+		return &vecNode{pos: pos, elem: &refNode{pos: pos, name: "nat8"}}, nil
+	case "record":
+		fields, err := p.parseFields(false)
+		if err != nil {
+			return nil, err
+		}
+		return &recordNode{pos: pos, fields: fields}, nil
+	case "variant":
+		fields, err := p.parseFields(true)
+		if err != nil {
+			return nil, err
+		}
+		return &variantNode{pos: pos, fields: fields}, nil
+	case "func":
+		return p.parseFunc(pos)
+	case "service":
+		return p.parseMethods(pos)
+	}
+	return &refNode{pos: pos, name: name}, nil
+}
+
+// parseFields parses the fields of a record or a variant. Record fields
+// without label are numbered from the previous one, and variant fields
+// without type are of type null.
+func (p *parser) parseFields(variant bool) ([]fieldNode, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var fields []fieldNode
+	next := big.NewInt(0)
+	for !p.is("}") {
+		pos := p.tok.Pos
+		f := fieldNode{pos: pos}
+		labeled := false
+		if p.tok.Kind == idl.TokenText || p.tok.Kind == idl.TokenNumber || p.tok.Kind == idl.TokenIdent {
+			// A label is followed by a colon, except for variant fields of
+			// type null.
+			tok := p.tok
+			lexer := *p.lexer
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			switch {
+			case p.is(":"):
+				labeled = true
+				if err := p.next(); err != nil {
+					return nil, err
+				}
+			case variant && (p.is(";") || p.is("}")):
+				labeled = true
+			default:
+				*p.lexer = lexer
+				p.tok = tok
+			}
+			if labeled {
+				label, err := p.label(tok)
+				if err != nil {
+					return nil, err
+				}
+				f.label = label
+			}
+		}
+		if !labeled && variant {
+			return nil, p.unexpected("variant field name")
+		}
+		if !labeled {
+			f.label = next.String()
+		}
+		if !(variant && (p.is(";") || p.is("}"))) {
+			t, err := p.parseType()
+			if err != nil {
+				return nil, err
+			}
+			f.typ = t
+		}
+		if n, ok := new(big.Int).SetString(f.label, 10); ok {
+			next = n.Add(n, big.NewInt(1))
+		}
+		fields = append(fields, f)
+		if ok, err := p.accept(";"); err != nil {
+			return nil, err
+		} else if !ok {
+			break
+		}
+	}
+	if err := p.expect("}"); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// label returns the name of a field from its label token.
+func (p *parser) label(tok idl.Token) (string, error) {
+	if tok.Kind != idl.TokenNumber {
+		return tok.Value, nil
+	}
+	n, ok := tok.Nat()
+	if !ok || n.BitLen() > 32 {
+		return "", p.errorf(tok.Pos, "invalid field id %s", tok.Value)
+	}
+	return n.String(), nil
+}
+
+// parseTuple parses a parenthesized list of types, which may be named.
+func (p *parser) parseTuple() ([]typeNode, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var ts []typeNode
+	for !p.is(")") {
+		if p.tok.Kind == idl.TokenIdent || p.tok.Kind == idl.TokenText {
+			// Argument names are documentation only.
+			tok := p.tok
+			lexer := *p.lexer
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			if ok, err := p.accept(":"); err != nil {
+				return nil, err
+			} else if !ok {
+				*p.lexer = lexer
+				p.tok = tok
+			}
+		}
+		t, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		ts = append(ts, t)
+		if ok, err := p.accept(","); err != nil {
+			return nil, err
+		} else if !ok {
+			break
+		}
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return ts, nil
+}
+
+func (p *parser) parseFunc(pos idl.Position) (*funcNode, error) {
+	args, err := p.parseTuple()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect("->"); err != nil {
+		return nil, err
+	}
+	rets, err := p.parseTuple()
+	if err != nil {
+		return nil, err
+	}
+	f := &funcNode{pos: pos, args: args, rets: rets}
+	for p.is("query") || p.is("oneway") || p.is("composite_query") {
+		f.annotations = append(f.annotations, p.tok.Value)
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+func (p *parser) parseMethods(pos idl.Position) (*serviceNode, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	s := &serviceNode{pos: pos}
+	for !p.is("}") {
+		m := methodNode{pos: p.tok.Pos, name: p.tok.Value}
+		if p.tok.Kind != idl.TokenIdent && p.tok.Kind != idl.TokenText {
+			return nil, p.unexpected("method name")
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if p.is("(") {
+			f, err := p.parseFunc(p.tok.Pos)
+			if err != nil {
+				return nil, err
+			}
+			m.typ = f
+		} else {
+			if p.tok.Kind != idl.TokenIdent {
+				return nil, p.unexpected("function type")
+			}
+			m.typ = &refNode{pos: p.tok.Pos, name: p.tok.Value}
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+		}
+		s.methods = append(s.methods, m)
+		if ok, err := p.accept(";"); err != nil {
+			return nil, err
+		} else if !ok {
+			break
+		}
+	}
+	if err := p.expect("}"); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
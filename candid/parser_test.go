@@ -0,0 +1,95 @@
+package candid_test
+
+import (
+	"testing"
+
+	"github.com/icpfans-xyz/agent-go/candid"
+	"github.com/icpfans-xyz/agent-go/candid/idl"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("types", func(t *testing.T) {
+		program, err := candid.Parse(`
+			type List = opt record { head : int; tail : List };
+			type Pair = record { text; nat; 5 : bool; bool; 010 : nat8 };
+			type Status = variant { active; "inactive" : text; 0x10 : null };
+			type Callback = func (nat) -> (text) composite_query;
+			type Escapes = record { "\u{1F600}\t\"" : null };
+		`)
+		assert.NoError(t, err)
+		assert.Len(t, program.Types, 5)
+		assert.Nil(t, program.Service)
+		assert.Equal(t, "opt record {head:int; tail:List}", program.Types["List"].Type().String())
+		assert.Equal(t, "record {0:text; 1:nat; 5:bool; 6:bool; 10:nat8}", program.Types["Pair"].Type().String())
+		assert.Equal(t, "variant {16:null; active:null; inactive:text}", program.Types["Status"].Type().String())
+		assert.Equal(t, "(nat) -> (text) composite_query", program.Types["Callback"].Type().String())
+		assert.Equal(t, "record {\U0001F600\t\":null}", program.Types["Escapes"].Type().String())
+
+		bs, err := idl.Encode([]idl.Type{program.Types["List"]}, []interface{}{nil})
+		assert.NoError(t, err)
+		assert.Equal(t, "4449444c036e016c02a0d2aca8047c90eddae704006e01010000", hexString(bs))
+	})
+
+	t.Run("file", func(t *testing.T) {
+		program, err := candid.ParseFile("testdata/ledger.did")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"account.did"}, program.Imports)
+		assert.Contains(t, program.Types, "Account")
+		assert.Equal(t, "record {owner:principal; subaccount:opt Subaccount}", program.Types["Account"].Type().String())
+
+		actor := program.Service
+		assert.Equal(t, []idl.Type{idl.NewRec(map[string]idl.Type{
+			"minting_account": program.Types["Account"],
+		})}, actor.InitArgs)
+		var names []string
+		for _, m := range actor.Service.Methods() {
+			names = append(names, m.Name)
+		}
+		assert.Equal(t, []string{"icrc1_balance_of", "icrc1_metadata", "icrc1_transfer", "notify"}, names)
+		methods := actor.Service.Methods()
+		assert.Equal(t, "(Account) -> (Tokens) query", methods[0].Func.String())
+		assert.Equal(t, "() -> (vec record {0:text; 1:Value}) query", methods[1].Func.String())
+		assert.Equal(t, []string{"oneway"}, methods[3].Func.Annotations)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for _, test := range []struct {
+			did string
+			err string
+		}{
+			{"type A = nat", `1:13: expected ";", found end of input`},
+			{"type A = record {\n  a : B;\n};", "2:7: undefined type B"},
+			{"type A = B;\ntype B = A;", "1:1: cyclic type definition A"},
+			{"type A = nat;\ntype A = int;", "2:1: duplicate type definition A"},
+			{"type A = record { a : nat; a : int };", "1:28: duplicate field a"},
+			{"type A = record { a : nat; 97 : int };", "1:28: field 97 has the same id as a"},
+			{"type A = variant { nat; : nat };", `1:25: expected variant field name, found ":"`},
+			{"type A = \"a\u00e9\"", `1:10: expected type, found "aé"`},
+			{"/* a\n", "1:1: unterminated comment"},
+			{"type A = nat;\n  service : { m : A }", "2:19: A is not a function type"},
+			{"service : nat", "1:11: nat is not a service"},
+			{"type A = nat; service : A", "1:25: A is not a service"},
+			{"type A = nat; @", "1:15: unexpected character '@'"},
+			{"type A = record { \"\\q\" : nat };", "1:20: invalid escape"},
+			{"type A = record { \"\\ff\" : nat };", "1:19: text is not valid UTF-8"},
+			{"type A = record { \"\\ff\" : nat };", "1:19: text is not valid UTF-8"},
+		} {
+			_, err := candid.Parse(test.did)
+			if assert.Error(t, err, test.did) {
+				assert.Equal(t, test.err, err.Error(), test.did)
+			}
+		}
+		_, err := candid.ParseFile("testdata/missing.did")
+		assert.Error(t, err)
+	})
+}
+
+func hexString(bs []byte) string {
+	const digits = "0123456789abcdef"
+	s := make([]byte, 0, 2*len(bs))
+	for _, b := range bs {
+		s = append(s, digits[b>>4], digits[b&0xf])
+	}
+	return string(s)
+}
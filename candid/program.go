@@ -0,0 +1,321 @@
+package candid
+
+import (
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/icpfans-xyz/agent-go/candid/idl"
+)
+
+// Program is a parsed .did file: see
+// https://github.com/dfinity/candid/blob/master/spec/Candid.md#core-grammar.
+type Program struct {
+	// The files imported by the program, as written.
+	Imports []string
+	// The type definitions, as knots named after the definitions so that
+	// they can be recursive.
+	Types map[string]*idl.Knot
+	// The main service of the program, nil if there is none.
+	Service *Actor
+}
+
+// Actor is the main service of a program.
+type Actor struct {
+	// The name of the service, if any.
+	Name string
+	// The arguments of the service when it is installed.
+	InitArgs []idl.Type
+	Service  *idl.Service
+}
+
+// Parse parses the text of a .did file. Imports are recorded but not
+// followed, so the program cannot use types of imported files: see
+// ParseFile.
+func Parse(did string) (*Program, error) {
+	f, err := parse("", did)
+	if err != nil {
+		return nil, err
+	}
+	return build(f, f.definitions, "")
+}
+
+// ParseFile parses a .did file, together with the definitions of the files
+// it imports, relative to its directory.
+func ParseFile(path string) (*Program, error) {
+	f, err := parseFile(path)
+	if err != nil {
+		return nil, err
+	}
+	definitions, err := importDefinitions(path, f, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+	return build(f, definitions, path)
+}
+
+func parseFile(path string) (*fileNode, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parse(path, string(data))
+}
+
+// importDefinitions returns the definitions of the file and of the files it
+// imports, transitively. Files are imported once.
+func importDefinitions(path string, f *fileNode, visited map[string]bool) ([]definitionNode, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	visited[abs] = true
+	var definitions []definitionNode
+	for _, i := range f.imports {
+		imported := filepath.Join(filepath.Dir(path), i.path)
+		abs, err := filepath.Abs(imported)
+		if err != nil {
+			return nil, err
+		}
+		if visited[abs] {
+			continue
+		}
+		g, err := parseFile(imported)
+		if err != nil {
+			return nil, &ParseError{
+				File:    path,
+				Line:    i.pos.Line,
+				Column:  i.pos.Column,
+				Message: err.Error(),
+			}
+		}
+		ds, err := importDefinitions(imported, g, visited)
+		if err != nil {
+			return nil, err
+		}
+		definitions = append(definitions, ds...)
+	}
+	return append(definitions, f.definitions...), nil
+}
+
+// builder turns the syntax tree into idl types. Definitions are built on
+// demand, so that they can be used before they are defined.
+type builder struct {
+	file        string
+	definitions map[string]definitionNode
+	knots       map[string]*idl.Knot
+	built       map[string]bool
+}
+
+func build(f *fileNode, definitions []definitionNode, file string) (*Program, error) {
+	b := &builder{
+		file:        file,
+		definitions: make(map[string]definitionNode),
+		knots:       make(map[string]*idl.Knot),
+		built:       make(map[string]bool),
+	}
+	for _, d := range definitions {
+		if _, ok := b.definitions[d.name]; ok {
+			return nil, b.errorf(d.file, d.pos, "duplicate type definition %s", d.name)
+		}
+		b.definitions[d.name] = d
+		b.knots[d.name] = idl.NewKnot(d.name)
+	}
+	for _, d := range definitions {
+		if _, err := b.definition(d.name); err != nil {
+			return nil, err
+		}
+	}
+	program := &Program{Types: b.knots}
+	for _, i := range f.imports {
+		program.Imports = append(program.Imports, i.path)
+	}
+	if f.service != nil {
+		actor := &Actor{Name: f.serviceName}
+		service := f.service
+		if class, ok := service.(*classNode); ok {
+			args, err := b.types(class.args)
+			if err != nil {
+				return nil, err
+			}
+			actor.InitArgs = args
+			service = class.service
+		}
+		s, err := b.typ(service)
+		if err != nil {
+			return nil, err
+		}
+		var ok bool
+		if actor.Service, ok = resolve(s).(*idl.Service); !ok {
+			return nil, b.errorf(b.file, service.position(), "%s is not a service", s)
+		}
+		program.Service = actor
+	}
+	return program, nil
+}
+
+func (b *builder) errorf(file string, pos idl.Position, format string, args ...interface{}) error {
+	return parseError(file, pos, format, args...)
+}
+
+// resolve follows knots to the type they refer to.
+func resolve(t idl.Type) idl.Type {
+	for {
+		k, ok := t.(*idl.Knot)
+		if !ok || k.Type() == nil {
+			return t
+		}
+		t = k.Type()
+	}
+}
+
+// definition returns the knot of the definition, building it the first
+// time. Within its own definition, the knot is returned unfilled.
+func (b *builder) definition(name string) (*idl.Knot, error) {
+	k := b.knots[name]
+	if b.built[name] {
+		return k, nil
+	}
+	b.built[name] = true
+	d := b.definitions[name]
+	file := b.file
+	b.file = d.file
+	defer func() { b.file = file }()
+	t, err := b.typ(d.typ)
+	if err != nil {
+		return nil, err
+	}
+	// A definition that is an alias of itself has no type.
+	if err := k.Fill(t); err != nil {
+		return nil, b.errorf(d.file, d.pos, "cyclic type definition %s", name)
+	}
+	return k, nil
+}
+
+var primitives = map[string]func() idl.Type{
+	"nat":       func() idl.Type { return new(idl.Nat) },
+	"nat8":      func() idl.Type { return idl.Nat8() },
+	"nat16":     func() idl.Type { return idl.Nat16() },
+	"nat32":     func() idl.Type { return idl.Nat32() },
+	"nat64":     func() idl.Type { return idl.Nat64() },
+	"int":       func() idl.Type { return new(idl.Int) },
+	"int8":      func() idl.Type { return idl.Int8() },
+	"int16":     func() idl.Type { return idl.Int16() },
+	"int32":     func() idl.Type { return idl.Int32() },
+	"int64":     func() idl.Type { return idl.Int64() },
+	"float32":   func() idl.Type { return idl.Float32() },
+	"float64":   func() idl.Type { return idl.Float64() },
+	"bool":      func() idl.Type { return new(idl.Bool) },
+	"text":      func() idl.Type { return new(idl.Text) },
+	"null":      func() idl.Type { return new(idl.Null) },
+	"reserved":  func() idl.Type { return new(idl.Reserved) },
+	"empty":     func() idl.Type { return new(idl.Empty) },
+	"principal": func() idl.Type { return new(idl.Principal) },
+}
+
+func (b *builder) types(ns []typeNode) ([]idl.Type, error) {
+	var ts []idl.Type
+	for _, n := range ns {
+		t, err := b.typ(n)
+		if err != nil {
+			return nil, err
+		}
+		ts = append(ts, t)
+	}
+	return ts, nil
+}
+
+func (b *builder) typ(n typeNode) (idl.Type, error) {
+	switch n := n.(type) {
+	case *refNode:
+		if _, ok := b.definitions[n.name]; ok {
+			return b.definition(n.name)
+		}
+		if prim, ok := primitives[n.name]; ok {
+			return prim(), nil
+		}
+		return nil, b.errorf(b.file, n.pos, "undefined type %s", n.name)
+	case *optNode:
+		t, err := b.typ(n.elem)
+		if err != nil {
+			return nil, err
+		}
+		return idl.NewOpt(t), nil
+	case *vecNode:
+		t, err := b.typ(n.elem)
+		if err != nil {
+			return nil, err
+		}
+		return idl.NewVec(t), nil
+	case *recordNode:
+		fields, err := b.fields(n.fields)
+		if err != nil {
+			return nil, err
+		}
+		return idl.NewRec(fields), nil
+	case *variantNode:
+		fields, err := b.fields(n.fields)
+		if err != nil {
+			return nil, err
+		}
+		return idl.NewVariant(fields), nil
+	case *funcNode:
+		return b.function(n)
+	case *serviceNode:
+		methods := make(map[string]*idl.Func)
+		for _, m := range n.methods {
+			if _, ok := methods[m.name]; ok {
+				return nil, b.errorf(b.file, m.pos, "duplicate method %s", m.name)
+			}
+			t, err := b.typ(m.typ)
+			if err != nil {
+				return nil, err
+			}
+			f, ok := resolve(t).(*idl.Func)
+			if !ok {
+				return nil, b.errorf(b.file, m.typ.position(), "%s is not a function type", t)
+			}
+			methods[m.name] = f
+		}
+		return idl.NewService(methods), nil
+	case *classNode:
+		return nil, b.errorf(b.file, n.pos, "service constructors are only allowed for the main service")
+	}
+	return nil, b.errorf(b.file, n.position(), "unexpected type")
+}
+
+func (b *builder) fields(ns []fieldNode) (map[string]idl.Type, error) {
+	fields := make(map[string]idl.Type)
+	ids := make(map[string]string)
+	for _, f := range ns {
+		id := idl.LabelId(f.label).String()
+		if other, ok := ids[id]; ok {
+			if other == f.label {
+				return nil, b.errorf(b.file, f.pos, "duplicate field %s", f.label)
+			}
+			return nil, b.errorf(b.file, f.pos, "field %s has the same id as %s", f.label, other)
+		}
+		ids[id] = f.label
+		if f.typ == nil {
+			fields[f.label] = new(idl.Null)
+			continue
+		}
+		t, err := b.typ(f.typ)
+		if err != nil {
+			return nil, err
+		}
+		fields[f.label] = t
+	}
+	return fields, nil
+}
+
+func (b *builder) function(n *funcNode) (*idl.Func, error) {
+	args, err := b.types(n.args)
+	if err != nil {
+		return nil, err
+	}
+	rets, err := b.types(n.rets)
+	if err != nil {
+		return nil, err
+	}
+	return idl.NewFunc(args, rets, n.annotations), nil
+}
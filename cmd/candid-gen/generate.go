@@ -0,0 +1,979 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/icpfans-xyz/agent-go/candid"
+	"github.com/icpfans-xyz/agent-go/candid/idl"
+)
+
+const (
+	agentPackage     = "github.com/icpfans-xyz/agent-go/agent"
+	idlPackage       = "github.com/icpfans-xyz/agent-go/candid/idl"
+	principalPackage = "github.com/icpfans-xyz/agent-go/principal"
+)
+
+type options struct {
+	// The name of the generated package.
+	Package string
+	// The name of the client type.
+	Client string
+	// The .did file the code is generated from, mentioned in the header.
+	Source string
+}
+
+// generator turns the types of a program into Go declarations. Records
+// become structs and variants interfaces with one type per case, and the
+// anonymous ones are named after where they are used. Every declared type
+// has functions converting it to and from the values of the idl package:
+// encodeT and decodeT.
+type generator struct {
+	program *candid.Program
+	options options
+	// The Go names of the type definitions.
+	names map[*idl.Knot]string
+	// The Go names of the anonymous records and variants.
+	anon map[idl.Type]string
+	// The package-level Go names in use.
+	used    map[string]bool
+	imports map[string]bool
+	types   bytes.Buffer
+	// The number of temporary variables of the conversions.
+	tmps int
+}
+
+// generate returns the Go source of the types and of the client of the
+// program.
+func generate(program *candid.Program, opts options) ([]byte, error) {
+	g := &generator{
+		program: program,
+		options: opts,
+		names:   make(map[*idl.Knot]string),
+		anon:    make(map[idl.Type]string),
+		used: map[string]bool{
+			opts.Client:                        true,
+			"New" + opts.Client:                true,
+			"New" + opts.Client + "WithConfig": true,
+			"EncodeInitArgs":                   true,
+		},
+		imports: make(map[string]bool),
+	}
+	var definitions []string
+	for name := range program.Types {
+		definitions = append(definitions, name)
+	}
+	sort.Strings(definitions)
+	for _, name := range definitions {
+		g.names[program.Types[name]] = g.newName(goName(name))
+	}
+	for _, name := range definitions {
+		if err := g.definition(program.Types[name]); err != nil {
+			return nil, fmt.Errorf("type %s: %w", name, err)
+		}
+	}
+	var client bytes.Buffer
+	if program.Service != nil {
+		if err := g.client(&client, program.Service); err != nil {
+			return nil, err
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by candid-gen")
+	if opts.Source != "" {
+		fmt.Fprintf(&out, " from %s", opts.Source)
+	}
+	fmt.Fprintf(&out, ". DO NOT EDIT.\n\npackage %s\n\n", opts.Package)
+	// The standard library first, then the packages of the modules.
+	var std, module []string
+	for i := range g.imports {
+		if strings.Contains(i, ".") {
+			module = append(module, i)
+		} else {
+			std = append(std, i)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(module)
+	if len(g.imports) != 0 {
+		fmt.Fprintf(&out, "import (\n")
+		for _, i := range std {
+			fmt.Fprintf(&out, "%q\n", i)
+		}
+		if len(std) != 0 && len(module) != 0 {
+			fmt.Fprintf(&out, "\n")
+		}
+		for _, i := range module {
+			fmt.Fprintf(&out, "%q\n", i)
+		}
+		fmt.Fprintf(&out, ")\n\n")
+	}
+	out.Write(g.types.Bytes())
+	out.Write(client.Bytes())
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("invalid generated code: %w", err)
+	}
+	return src, nil
+}
+
+// goName converts a Candid name to an exported Go name.
+func goName(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	s := b.String()
+	if s == "" || unicode.IsDigit(rune(s[0])) {
+		s = "X" + s
+	}
+	return s
+}
+
+// fieldName converts the label of a record or variant field to a Go name.
+func fieldName(label string) string {
+	if _, err := strconv.ParseUint(label, 10, 32); err == nil {
+		return "Field" + label
+	}
+	return goName(label)
+}
+
+// newName returns an unused package-level name.
+func (g *generator) newName(name string) string {
+	unique := name
+	for i := 2; g.used[unique]; i++ {
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+	g.used[unique] = true
+	return unique
+}
+
+func resolve(t idl.Type) idl.Type {
+	for {
+		k, ok := t.(*idl.Knot)
+		if !ok {
+			return t
+		}
+		t = k.Type()
+	}
+}
+
+// isUnit reports whether the type has no values to speak of, which map to
+// struct{}.
+func isUnit(t idl.Type) bool {
+	switch resolve(t).(type) {
+	case *idl.Null, *idl.Reserved, *idl.Empty:
+		return true
+	}
+	return false
+}
+
+// nilable reports whether nil is not a value of the Go type of the type, so
+// that nil can stand for null in an opt of the type, without a pointer.
+func nilable(t idl.Type) bool {
+	switch t := resolve(t).(type) {
+	case *idl.Nat:
+		return t.Base == 0
+	case *idl.Int:
+		return t.Base == 0
+	case *idl.Variant:
+		return true
+	}
+	return false
+}
+
+// refersTo reports whether the type refers to the knot.
+func refersTo(t idl.Type, k *idl.Knot, visited map[idl.Type]bool) bool {
+	if visited[t] {
+		return false
+	}
+	visited[t] = true
+	switch t := t.(type) {
+	case *idl.Knot:
+		return t == k || refersTo(t.Type(), k, visited)
+	case *idl.Opt:
+		return refersTo(t.Type, k, visited)
+	case *idl.Vec:
+		return refersTo(t.Type, k, visited)
+	case *idl.Rec:
+		for _, f := range t.Fields {
+			if refersTo(f.Type, k, visited) {
+				return true
+			}
+		}
+	case *idl.Variant:
+		for _, f := range t.Fields {
+			if refersTo(f.Type, k, visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// definition declares the Go type of a type definition: a struct for a
+// record, an interface for a variant and an alias otherwise.
+func (g *generator) definition(k *idl.Knot) error {
+	name := g.names[k]
+	switch t := k.Type().(type) {
+	case *idl.Rec:
+		return g.recordType(name, t)
+	case *idl.Variant:
+		return g.variantType(name, t)
+	}
+	t := k.Type()
+	typ, err := g.goType(t, name)
+	if err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	// Go aliases cannot be recursive.
+	if refersTo(t, k, map[idl.Type]bool{}) {
+		fmt.Fprintf(&b, "type %s %s\n\n", name, typ)
+	} else {
+		fmt.Fprintf(&b, "type %s = %s\n\n", name, typ)
+	}
+	fmt.Fprintf(&b, "func encode%s(v %s) (r interface{}, err error) {\n", name, name)
+	if err := g.encode(&b, t, "r", "v"); err != nil {
+		return err
+	}
+	fmt.Fprintf(&b, "return\n}\n\n")
+	fmt.Fprintf(&b, "func decode%s(v interface{}) (r %s, err error) {\n", name, name)
+	if err := g.decode(&b, t, "r", "v"); err != nil {
+		return err
+	}
+	fmt.Fprintf(&b, "return\n}\n\n")
+	g.types.Write(b.Bytes())
+	return nil
+}
+
+// goType returns the Go type of the Candid type, declaring the anonymous
+// records and variants, named after the context.
+func (g *generator) goType(t idl.Type, context string) (string, error) {
+	if k, ok := t.(*idl.Knot); ok {
+		if name, ok := g.names[k]; ok {
+			return name, nil
+		}
+		return g.goType(k.Type(), context)
+	}
+	switch t := t.(type) {
+	case *idl.Null, *idl.Reserved, *idl.Empty:
+		return "struct{}", nil
+	case *idl.Bool:
+		return "bool", nil
+	case *idl.Text:
+		return "string", nil
+	case *idl.Nat:
+		if t.Base == 0 {
+			g.imports["math/big"] = true
+			return "*big.Int", nil
+		}
+		return fmt.Sprintf("uint%d", t.Base), nil
+	case *idl.Int:
+		if t.Base == 0 {
+			g.imports["math/big"] = true
+			return "*big.Int", nil
+		}
+		return fmt.Sprintf("int%d", t.Base), nil
+	case *idl.Float:
+		return fmt.Sprintf("float%d", t.Base), nil
+	case *idl.Principal, *idl.Service:
+		g.imports[principalPackage] = true
+		return "principal.Principal", nil
+	case *idl.Func:
+		g.imports[idlPackage] = true
+		return "idl.PrincipalMethod", nil
+	case *idl.Opt:
+		elem, err := g.goType(t.Type, context)
+		if err != nil {
+			return "", err
+		}
+		if nilable(t.Type) {
+			return elem, nil
+		}
+		return "*" + elem, nil
+	case *idl.Vec:
+		if n, ok := resolve(t.Type).(*idl.Nat); ok && n.Base == 8 {
+			return "[]byte", nil
+		}
+		elem, err := g.goType(t.Type, context+"Item")
+		if err != nil {
+			return "", err
+		}
+		return "[]" + elem, nil
+	case *idl.Rec:
+		if name, ok := g.anon[t]; ok {
+			return name, nil
+		}
+		name := g.newName(context)
+		g.anon[t] = name
+		return name, g.recordType(name, t)
+	case *idl.Variant:
+		if name, ok := g.anon[t]; ok {
+			return name, nil
+		}
+		name := g.newName(context)
+		g.anon[t] = name
+		return name, g.variantType(name, t)
+	}
+	return "", fmt.Errorf("unsupported type %s", t)
+}
+
+// structField is a field of a generated struct.
+type structField struct {
+	name  string
+	label string
+	t     idl.Type
+	typ   string
+}
+
+// recordType declares the struct of a record and its conversions.
+func (g *generator) recordType(name string, t *idl.Rec) error {
+	var fields []structField
+	names := make(map[string]bool)
+	for _, f := range t.Fields {
+		field := fieldName(f.Name)
+		unique := field
+		for i := 2; names[unique]; i++ {
+			unique = fmt.Sprintf("%s%d", field, i)
+		}
+		names[unique] = true
+		typ, err := g.goType(f.Type, name+field)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", f.Name, err)
+		}
+		fields = append(fields, structField{name: unique, label: f.Name, t: f.Type, typ: typ})
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "type %s struct {\n", name)
+	for _, f := range fields {
+		fmt.Fprintf(&b, "%s %s\n", f.name, f.typ)
+	}
+	fmt.Fprintf(&b, "}\n\n")
+
+	fmt.Fprintf(&b, "func encode%s(v %s) (r interface{}, err error) {\n", name, name)
+	fmt.Fprintf(&b, "rec := make(map[string]interface{}, %d)\n", len(fields))
+	for _, f := range fields {
+		if err := g.encode(&b, f.t, fmt.Sprintf("rec[%q]", f.label), "v."+f.name); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(&b, "r = rec\nreturn\n}\n\n")
+
+	fmt.Fprintf(&b, "func decode%s(v interface{}) (r %s, err error) {\n", name, name)
+	rec := "rec"
+	if len(fields) == 0 {
+		rec = "_"
+	}
+	fmt.Fprintf(&b, "%s, ok := v.(map[string]interface{})\nif !ok && v != nil {\n", rec)
+	g.fail(&b, "v", name)
+	fmt.Fprintf(&b, "}\n")
+	for _, f := range fields {
+		if err := g.decode(&b, f.t, "r."+f.name, fmt.Sprintf("rec[%q]", f.label)); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(&b, "return\n}\n\n")
+	g.types.Write(b.Bytes())
+	return nil
+}
+
+// variantCase is a case of a generated variant: a struct with the fields of
+// an anonymous record, a Value field or no field.
+type variantCase struct {
+	name  string
+	label string
+	t     idl.Type
+	// The record whose fields are those of the case.
+	rec *idl.Rec
+}
+
+// variantType declares the interface of a variant, the types of its cases
+// and its conversions.
+func (g *generator) variantType(name string, t *idl.Variant) error {
+	g.imports[idlPackage] = true
+	var cases []variantCase
+	var names []string
+	for _, f := range t.Fields {
+		c := variantCase{name: g.newName(name + fieldName(f.Name)), label: f.Name, t: f.Type}
+		if rec, ok := f.Type.(*idl.Rec); ok {
+			c.rec = rec
+		}
+		cases = append(cases, c)
+		names = append(names, c.name)
+	}
+	fmt.Fprintf(&g.types, "// %s is a variant: ", name)
+	if len(names) == 0 {
+		fmt.Fprintf(&g.types, "it has no values.\n")
+	} else {
+		fmt.Fprintf(&g.types, "one of %s.\n", strings.Join(names, ", "))
+	}
+	fmt.Fprintf(&g.types, "type %s interface {\nis%s()\n}\n\n", name, name)
+
+	var b bytes.Buffer
+	usesCase := false
+	for _, c := range cases {
+		switch {
+		case c.rec != nil:
+			g.anon[c.rec] = c.name
+			if err := g.recordType(c.name, c.rec); err != nil {
+				return fmt.Errorf("field %s: %w", c.label, err)
+			}
+			usesCase = true
+		case isUnit(c.t):
+			fmt.Fprintf(&g.types, "type %s struct{}\n\n", c.name)
+		default:
+			typ, err := g.goType(c.t, c.name+"Value")
+			if err != nil {
+				return fmt.Errorf("field %s: %w", c.label, err)
+			}
+			fmt.Fprintf(&g.types, "type %s struct {\nValue %s\n}\n\n", c.name, typ)
+			usesCase = true
+		}
+		fmt.Fprintf(&b, "func (%s) is%s() {}\n", c.name, name)
+	}
+	if len(cases) != 0 {
+		fmt.Fprintf(&b, "\n")
+	}
+
+	g.imports["fmt"] = true
+	fmt.Fprintf(&b, "func encode%s(v %s) (r interface{}, err error) {\n", name, name)
+	if usesCase {
+		fmt.Fprintf(&b, "switch c := v.(type) {\n")
+	} else {
+		fmt.Fprintf(&b, "switch v.(type) {\n")
+	}
+	for _, c := range cases {
+		fmt.Fprintf(&b, "case %s:\n", c.name)
+		switch {
+		case c.rec != nil:
+			fmt.Fprintf(&b, "fv := idl.FieldValue{Name: %q}\n", c.label)
+			if err := g.encode(&b, c.rec, "fv.Value", "c"); err != nil {
+				return err
+			}
+			fmt.Fprintf(&b, "r = fv\n")
+		case isUnit(c.t):
+			fmt.Fprintf(&b, "r = idl.FieldValue{Name: %q}\n", c.label)
+		default:
+			fmt.Fprintf(&b, "fv := idl.FieldValue{Name: %q}\n", c.label)
+			if err := g.encode(&b, c.t, "fv.Value", "c.Value"); err != nil {
+				return err
+			}
+			fmt.Fprintf(&b, "r = fv\n")
+		}
+	}
+	fmt.Fprintf(&b, "default:\nerr = fmt.Errorf(%q, v)\n}\nreturn\n}\n\n", "candid: cannot encode %T as "+name)
+
+	fmt.Fprintf(&b, "func decode%s(v interface{}) (r %s, err error) {\n", name, name)
+	fmt.Fprintf(&b, "fv, ok := v.(*idl.FieldValue)\nif !ok {\n")
+	g.fail(&b, "v", name)
+	fmt.Fprintf(&b, "}\nswitch fv.Name {\n")
+	for _, c := range cases {
+		fmt.Fprintf(&b, "case %q:\n", c.label)
+		switch {
+		case c.rec != nil:
+			fmt.Fprintf(&b, "var c %s\n", c.name)
+			if err := g.decode(&b, c.rec, "c", "fv.Value"); err != nil {
+				return err
+			}
+			fmt.Fprintf(&b, "r = c\n")
+		case isUnit(c.t):
+			fmt.Fprintf(&b, "r = %s{}\n", c.name)
+		default:
+			fmt.Fprintf(&b, "var c %s\n", c.name)
+			if err := g.decode(&b, c.t, "c.Value", "fv.Value"); err != nil {
+				return err
+			}
+			fmt.Fprintf(&b, "r = c\n")
+		}
+	}
+	g.imports["fmt"] = true
+	fmt.Fprintf(&b, "default:\nerr = fmt.Errorf(%q, fv.Name)\n}\nreturn\n}\n\n", "candid: unknown case %s of "+name)
+	g.types.Write(b.Bytes())
+	return nil
+}
+
+// tmp returns a number for the names of temporary variables.
+func (g *generator) tmp() int {
+	g.tmps++
+	return g.tmps
+}
+
+// index returns the expression of an element of the slice x.
+func index(x, i string) string {
+	if strings.HasPrefix(x, "*") {
+		x = "(" + x + ")"
+	}
+	return x + "[" + i + "]"
+}
+
+// encode writes the statements converting the Go value src of the type to
+// the value dst of the idl package. The statements return from a function
+// with a named err result if src cannot be encoded.
+func (g *generator) encode(b *bytes.Buffer, t idl.Type, dst, src string) error {
+	if k, ok := t.(*idl.Knot); ok {
+		if name, ok := g.names[k]; ok {
+			fmt.Fprintf(b, "%s, err = encode%s(%s)\nif err != nil {\nreturn\n}\n", dst, name, src)
+			return nil
+		}
+		return g.encode(b, k.Type(), dst, src)
+	}
+	switch t := t.(type) {
+	case *idl.Null, *idl.Reserved, *idl.Empty:
+		fmt.Fprintf(b, "%s = nil\n", dst)
+	case *idl.Bool, *idl.Text, *idl.Func:
+		fmt.Fprintf(b, "%s = %s\n", dst, src)
+	case *idl.Nat:
+		if t.Base == 0 {
+			fmt.Fprintf(b, "%s = %s\n", dst, src)
+			break
+		}
+		g.imports["math/big"] = true
+		fmt.Fprintf(b, "%s = new(big.Int).SetUint64(uint64(%s))\n", dst, src)
+	case *idl.Int:
+		if t.Base == 0 {
+			fmt.Fprintf(b, "%s = %s\n", dst, src)
+			break
+		}
+		g.imports["math/big"] = true
+		fmt.Fprintf(b, "%s = big.NewInt(int64(%s))\n", dst, src)
+	case *idl.Float:
+		// A big.Float cannot be NaN.
+		g.imports["errors"] = true
+		g.imports["math"] = true
+		g.imports["math/big"] = true
+		fmt.Fprintf(b, "if math.IsNaN(float64(%s)) {\nerr = errors.New(\"candid: cannot encode NaN as float%d\")\nreturn\n}\n", src, t.Base)
+		fmt.Fprintf(b, "%s = big.NewFloat(float64(%s))\n", dst, src)
+	case *idl.Principal, *idl.Service:
+		g.imports[idlPackage] = true
+		fmt.Fprintf(b, "%s = idl.Interface(&idl.PrincipalValue{Value: %s})\n", dst, src)
+	case *idl.Opt:
+		elem := src
+		if !nilable(t.Type) {
+			elem = "*" + src
+		}
+		fmt.Fprintf(b, "if %s != nil {\n", src)
+		if err := g.encode(b, t.Type, dst, elem); err != nil {
+			return err
+		}
+		fmt.Fprintf(b, "}\n")
+	case *idl.Vec:
+		n := g.tmp()
+		fmt.Fprintf(b, "vs%d := make([]interface{}, len(%s))\nfor i%d := range vs%d {\n", n, src, n, n)
+		if err := g.encode(b, t.Type, fmt.Sprintf("vs%d[i%d]", n, n), index(src, fmt.Sprintf("i%d", n))); err != nil {
+			return err
+		}
+		fmt.Fprintf(b, "}\n%s = vs%d\n", dst, n)
+	case *idl.Rec, *idl.Variant:
+		name, ok := g.anon[t]
+		if !ok {
+			return fmt.Errorf("undeclared type %s", t)
+		}
+		fmt.Fprintf(b, "%s, err = encode%s(%s)\nif err != nil {\nreturn\n}\n", dst, name, src)
+	default:
+		return fmt.Errorf("unsupported type %s", t)
+	}
+	return nil
+}
+
+// fail writes the statements returning an error for a value src that is
+// not of the Go type.
+func (g *generator) fail(b *bytes.Buffer, src, typ string) {
+	g.imports["fmt"] = true
+	fmt.Fprintf(b, "err = fmt.Errorf(%q, %s)\nreturn\n", "candid: cannot decode %T as "+typ, src)
+}
+
+// assert writes the statements setting dst to the value src of the idl
+// package if it is a typ.
+func (g *generator) assert(b *bytes.Buffer, dst, src, typ string) {
+	fmt.Fprintf(b, "if x, ok := %s.(%s); ok {\n%s = x\n} else {\n", src, typ, dst)
+	g.fail(b, src, typ)
+	fmt.Fprintf(b, "}\n")
+}
+
+// decode writes the statements converting the value src of the idl package
+// to the Go value dst of the type. The statements return from a function
+// with a named err result if src is not a value of the type.
+func (g *generator) decode(b *bytes.Buffer, t idl.Type, dst, src string) error {
+	if k, ok := t.(*idl.Knot); ok {
+		if name, ok := g.names[k]; ok {
+			fmt.Fprintf(b, "%s, err = decode%s(%s)\nif err != nil {\nreturn\n}\n", dst, name, src)
+			return nil
+		}
+		return g.decode(b, k.Type(), dst, src)
+	}
+	switch t := t.(type) {
+	case *idl.Null, *idl.Reserved, *idl.Empty:
+	case *idl.Bool:
+		g.assert(b, dst, src, "bool")
+	case *idl.Text:
+		g.assert(b, dst, src, "string")
+	case *idl.Func:
+		g.assert(b, dst, src, "idl.PrincipalMethod")
+	case *idl.Nat:
+		if t.Base == 0 {
+			g.assert(b, dst, src, "*big.Int")
+			break
+		}
+		fmt.Fprintf(b, "if x, ok := %s.(*big.Int); ok && x.IsUint64() {\n%s = uint%d(x.Uint64())\n} else {\n", src, dst, t.Base)
+		g.fail(b, src, fmt.Sprintf("uint%d", t.Base))
+		fmt.Fprintf(b, "}\n")
+	case *idl.Int:
+		if t.Base == 0 {
+			g.assert(b, dst, src, "*big.Int")
+			break
+		}
+		fmt.Fprintf(b, "if x, ok := %s.(*big.Int); ok && x.IsInt64() {\n%s = int%d(x.Int64())\n} else {\n", src, dst, t.Base)
+		g.fail(b, src, fmt.Sprintf("int%d", t.Base))
+		fmt.Fprintf(b, "}\n")
+	case *idl.Float:
+		fmt.Fprintf(b, "if x, ok := %s.(*big.Float); ok {\nf, _ := x.Float64()\n%s = float%d(f)\n} else {\n", src, dst, t.Base)
+		g.fail(b, src, fmt.Sprintf("float%d", t.Base))
+		fmt.Fprintf(b, "}\n")
+	case *idl.Principal, *idl.Service:
+		g.imports[idlPackage] = true
+		n := g.tmp()
+		fmt.Fprintf(b, "var p%d idl.Value\np%d, err = idl.ValueOf(new(idl.Principal), %s)\nif err != nil {\nreturn\n}\n", n, n, src)
+		fmt.Fprintf(b, "%s = p%d.(*idl.PrincipalValue).Value\n", dst, n)
+	case *idl.Opt:
+		fmt.Fprintf(b, "if %s != nil {\n", src)
+		if nilable(t.Type) {
+			if err := g.decode(b, t.Type, dst, src); err != nil {
+				return err
+			}
+		} else {
+			typ, err := g.goType(t.Type, "")
+			if err != nil {
+				return err
+			}
+			n := g.tmp()
+			fmt.Fprintf(b, "var o%d %s\n", n, typ)
+			if err := g.decode(b, t.Type, fmt.Sprintf("o%d", n), src); err != nil {
+				return err
+			}
+			fmt.Fprintf(b, "%s = &o%d\n", dst, n)
+		}
+		fmt.Fprintf(b, "}\n")
+	case *idl.Vec:
+		typ, err := g.goType(t, "")
+		if err != nil {
+			return err
+		}
+		n := g.tmp()
+		fmt.Fprintf(b, "vs%d, ok := %s.([]interface{})\nif !ok && %s != nil {\n", n, src, src)
+		g.fail(b, src, typ)
+		fmt.Fprintf(b, "}\n%s = make(%s, len(vs%d))\nfor i%d := range vs%d {\n", dst, typ, n, n, n)
+		if err := g.decode(b, t.Type, index(dst, fmt.Sprintf("i%d", n)), fmt.Sprintf("vs%d[i%d]", n, n)); err != nil {
+			return err
+		}
+		fmt.Fprintf(b, "}\n")
+	case *idl.Rec, *idl.Variant:
+		name, ok := g.anon[t]
+		if !ok {
+			return fmt.Errorf("undeclared type %s", t)
+		}
+		fmt.Fprintf(b, "%s, err = decode%s(%s)\nif err != nil {\nreturn\n}\n", dst, name, src)
+	default:
+		return fmt.Errorf("unsupported type %s", t)
+	}
+	return nil
+}
+
+// typeExpr returns a Go expression of the Candid type.
+func (g *generator) typeExpr(t idl.Type) (string, error) {
+	switch t := t.(type) {
+	case *idl.Knot:
+		if name, ok := g.names[t]; ok {
+			return "type" + name, nil
+		}
+		return g.typeExpr(t.Type())
+	case *idl.Bool:
+		return "new(idl.Bool)", nil
+	case *idl.Text:
+		return "new(idl.Text)", nil
+	case *idl.Null:
+		return "new(idl.Null)", nil
+	case *idl.Reserved:
+		return "new(idl.Reserved)", nil
+	case *idl.Empty:
+		return "new(idl.Empty)", nil
+	case *idl.Principal:
+		return "new(idl.Principal)", nil
+	case *idl.Nat:
+		if t.Base == 0 {
+			return "new(idl.Nat)", nil
+		}
+		return fmt.Sprintf("idl.Nat%d()", t.Base), nil
+	case *idl.Int:
+		if t.Base == 0 {
+			return "new(idl.Int)", nil
+		}
+		return fmt.Sprintf("idl.Int%d()", t.Base), nil
+	case *idl.Float:
+		return fmt.Sprintf("idl.Float%d()", t.Base), nil
+	case *idl.Opt:
+		elem, err := g.typeExpr(t.Type)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("idl.NewOpt(%s)", elem), nil
+	case *idl.Vec:
+		elem, err := g.typeExpr(t.Type)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("idl.NewVec(%s)", elem), nil
+	case *idl.Rec:
+		fields, err := g.fieldsExpr(t.Fields)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("idl.NewRec(%s)", fields), nil
+	case *idl.Variant:
+		fields, err := g.fieldsExpr(t.Fields)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("idl.NewVariant(%s)", fields), nil
+	case *idl.Func:
+		return g.funcExpr(t)
+	case *idl.Service:
+		var b strings.Builder
+		b.WriteString("idl.NewService(map[string]*idl.Func{\n")
+		for _, m := range t.Methods() {
+			f, err := g.funcExpr(m.Func)
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintf(&b, "%q: %s,\n", m.Name, f)
+		}
+		b.WriteString("})")
+		return b.String(), nil
+	}
+	return "", fmt.Errorf("unsupported type %s", t)
+}
+
+func (g *generator) typesExpr(ts []idl.Type) (string, error) {
+	var b strings.Builder
+	b.WriteString("[]idl.Type{")
+	for i, t := range ts {
+		e, err := g.typeExpr(t)
+		if err != nil {
+			return "", err
+		}
+		if i != 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(e)
+	}
+	b.WriteString("}")
+	return b.String(), nil
+}
+
+func (g *generator) fieldsExpr(fields []idl.Field) (string, error) {
+	var b strings.Builder
+	b.WriteString("map[string]idl.Type{\n")
+	for _, f := range fields {
+		e, err := g.typeExpr(f.Type)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%q: %s,\n", f.Name, e)
+	}
+	b.WriteString("}")
+	return b.String(), nil
+}
+
+func (g *generator) funcExpr(f *idl.Func) (string, error) {
+	args, err := g.typesExpr(f.ArgTypes)
+	if err != nil {
+		return "", err
+	}
+	rets, err := g.typesExpr(f.RetTypes)
+	if err != nil {
+		return "", err
+	}
+	annotations := "nil"
+	if len(f.Annotations) != 0 {
+		var quoted []string
+		for _, a := range f.Annotations {
+			quoted = append(quoted, strconv.Quote(a))
+		}
+		annotations = fmt.Sprintf("[]string{%s}", strings.Join(quoted, ", "))
+	}
+	return fmt.Sprintf("idl.NewFunc(%s, %s, %s)", args, rets, annotations), nil
+}
+
+func hasAnnotation(f *idl.Func, annotation string) bool {
+	for _, a := range f.Annotations {
+		if a == annotation {
+			return true
+		}
+	}
+	return false
+}
+
+// client writes the Candid types of the definitions, the client type and
+// one method per method of the service.
+func (g *generator) client(b *bytes.Buffer, actor *candid.Actor) error {
+	g.imports[agentPackage] = true
+	g.imports[idlPackage] = true
+	g.imports[principalPackage] = true
+
+	var knots []*idl.Knot
+	for k := range g.names {
+		knots = append(knots, k)
+	}
+	sort.Slice(knots, func(i, j int) bool {
+		return g.names[knots[i]] < g.names[knots[j]]
+	})
+	if len(knots) != 0 {
+		fmt.Fprintf(b, "// The Candid types of the definitions.\nvar (\n")
+		for _, k := range knots {
+			fmt.Fprintf(b, "type%s = idl.NewKnot(%q)\n", g.names[k], k.Name)
+		}
+		fmt.Fprintf(b, ")\n\nfunc init() {\n")
+		for _, k := range knots {
+			e, err := g.typeExpr(k.Type())
+			if err != nil {
+				return fmt.Errorf("type %s: %w", k.Name, err)
+			}
+			fmt.Fprintf(b, "type%s.Fill(%s)\n", g.names[k], e)
+		}
+		fmt.Fprintf(b, "}\n\n")
+	}
+
+	client := g.options.Client
+	fmt.Fprintf(b, "// %s is a client of the canister.\n", client)
+	fmt.Fprintf(b, "type %s struct {\nconfig agent.CallConfig\n}\n\n", client)
+	fmt.Fprintf(b, "// New%s returns a client of the canister that calls it through the agent.\n", client)
+	fmt.Fprintf(b, "func New%s(a agent.Agent, canisterId principal.Principal) *%s {\n", client, client)
+	fmt.Fprintf(b, "return New%sWithConfig(agent.CallConfig{Agent: a, CanisterId: canisterId})\n}\n\n", client)
+	fmt.Fprintf(b, "// New%sWithConfig returns a client of the canister that calls it with the configuration.\n", client)
+	fmt.Fprintf(b, "func New%sWithConfig(config agent.CallConfig) *%s {\n", client, client)
+	fmt.Fprintf(b, "return &%s{config: config}\n}\n\n", client)
+
+	if len(actor.InitArgs) != 0 {
+		if err := g.method(b, "", "EncodeInitArgs", idl.NewFunc(actor.InitArgs, nil, nil)); err != nil {
+			return fmt.Errorf("init args: %w", err)
+		}
+	}
+	// Methods do not share the package-level names, and cannot be named
+	// config, the field of the client, since they are exported.
+	methods := make(map[string]bool)
+	for _, m := range actor.Service.Methods() {
+		name := goName(m.Name)
+		unique := name
+		for i := 2; methods[unique]; i++ {
+			unique = fmt.Sprintf("%s%d", name, i)
+		}
+		methods[unique] = true
+		if err := g.method(b, m.Name, unique, m.Func); err != nil {
+			return fmt.Errorf("method %s: %w", m.Name, err)
+		}
+	}
+	return nil
+}
+
+// method writes the client method of a service method, or the function
+// encoding the init args if the method has no name.
+func (g *generator) method(b *bytes.Buffer, method, name string, f *idl.Func) error {
+	expr, err := g.funcExpr(f)
+	if err != nil {
+		return err
+	}
+	funcVar := g.newName("func" + name)
+	fmt.Fprintf(b, "var %s = %s\n\n", funcVar, expr)
+
+	var params []string
+	var args bytes.Buffer
+	fmt.Fprintf(&args, "args := make([]interface{}, %d)\n", len(f.ArgTypes))
+	for i, t := range f.ArgTypes {
+		context := name + "Arg"
+		if len(f.ArgTypes) > 1 {
+			context += strconv.Itoa(i)
+		}
+		typ, err := g.goType(t, context)
+		if err != nil {
+			return err
+		}
+		params = append(params, fmt.Sprintf("arg%d %s", i, typ))
+		if err := g.encode(&args, t, fmt.Sprintf("args[%d]", i), fmt.Sprintf("arg%d", i)); err != nil {
+			return err
+		}
+	}
+	encode := fmt.Sprintf("idl.Encode(%s.ArgTypes, args)", funcVar)
+
+	if method == "" {
+		fmt.Fprintf(b, "// %s encodes the arguments of the installation of the canister.\n", name)
+		fmt.Fprintf(b, "func %s(%s) (arg []byte, err error) {\n", name, strings.Join(params, ", "))
+		b.Write(args.Bytes())
+		fmt.Fprintf(b, "return %s\n}\n\n", encode)
+		return nil
+	}
+
+	oneway := hasAnnotation(f, "oneway")
+	var results []string
+	if !oneway {
+		for i, t := range f.RetTypes {
+			context := name + "Result"
+			if len(f.RetTypes) > 1 {
+				context += strconv.Itoa(i)
+			}
+			typ, err := g.goType(t, context)
+			if err != nil {
+				return err
+			}
+			results = append(results, fmt.Sprintf("ret%d %s", i, typ))
+		}
+	}
+	rets := len(results)
+	results = append(results, "err error")
+
+	call := "Update"
+	kind := "update method"
+	switch {
+	case oneway:
+		call, kind = "Oneway", "oneway method"
+	case hasAnnotation(f, "query"):
+		call, kind = "Query", "query method"
+	case hasAnnotation(f, "composite_query"):
+		call, kind = "Query", "composite query method"
+	}
+	fmt.Fprintf(b, "// %s calls the %s %s.\n", name, kind, method)
+	fmt.Fprintf(b, "func (c *%s) %s(%s) (%s) {\n", g.options.Client, name, strings.Join(params, ", "), strings.Join(results, ", "))
+	b.Write(args.Bytes())
+	fmt.Fprintf(b, "arg, err := %s\nif err != nil {\nreturn\n}\n", encode)
+	switch {
+	case oneway:
+		fmt.Fprintf(b, "err = c.config.Oneway(%q, arg)\n", method)
+	case rets == 0:
+		fmt.Fprintf(b, "_, err = c.config.%s(%q, arg)\n", call, method)
+	default:
+		fmt.Fprintf(b, "reply, err := c.config.%s(%q, arg)\nif err != nil {\nreturn\n}\n", call, method)
+		fmt.Fprintf(b, "values, err := idl.DecodeAs(reply, %s.RetTypes)\nif err != nil {\nreturn\n}\n", funcVar)
+		for i, t := range f.RetTypes {
+			if err := g.decode(b, t, fmt.Sprintf("ret%d", i), fmt.Sprintf("values[%d]", i)); err != nil {
+				return err
+			}
+		}
+	}
+	fmt.Fprintf(b, "return\n}\n\n")
+	return nil
+}
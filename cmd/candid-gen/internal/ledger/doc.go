@@ -0,0 +1,6 @@
+// Package ledger is generated by candid-gen from the ledger of the test data
+// of the candid package, to check that the generated code builds and
+// converts values.
+package ledger
+
+//go:generate go run ../.. -package ledger -o ledger.go ../../../../candid/testdata/ledger.did
@@ -0,0 +1,804 @@
+// Code generated by candid-gen from ledger.did. DO NOT EDIT.
+
+package ledger
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/icpfans-xyz/agent-go/agent"
+	"github.com/icpfans-xyz/agent-go/candid/idl"
+	"github.com/icpfans-xyz/agent-go/principal"
+)
+
+type Account struct {
+	Owner      principal.Principal
+	Subaccount *Subaccount
+}
+
+func encodeAccount(v Account) (r interface{}, err error) {
+	rec := make(map[string]interface{}, 2)
+	rec["owner"] = idl.Interface(&idl.PrincipalValue{Value: v.Owner})
+	if v.Subaccount != nil {
+		rec["subaccount"], err = encodeSubaccount(*v.Subaccount)
+		if err != nil {
+			return
+		}
+	}
+	r = rec
+	return
+}
+
+func decodeAccount(v interface{}) (r Account, err error) {
+	rec, ok := v.(map[string]interface{})
+	if !ok && v != nil {
+		err = fmt.Errorf("candid: cannot decode %T as Account", v)
+		return
+	}
+	var p1 idl.Value
+	p1, err = idl.ValueOf(new(idl.Principal), rec["owner"])
+	if err != nil {
+		return
+	}
+	r.Owner = p1.(*idl.PrincipalValue).Value
+	if rec["subaccount"] != nil {
+		var o2 Subaccount
+		o2, err = decodeSubaccount(rec["subaccount"])
+		if err != nil {
+			return
+		}
+		r.Subaccount = &o2
+	}
+	return
+}
+
+type Subaccount = []byte
+
+func encodeSubaccount(v Subaccount) (r interface{}, err error) {
+	vs3 := make([]interface{}, len(v))
+	for i3 := range vs3 {
+		vs3[i3] = new(big.Int).SetUint64(uint64(v[i3]))
+	}
+	r = vs3
+	return
+}
+
+func decodeSubaccount(v interface{}) (r Subaccount, err error) {
+	vs4, ok := v.([]interface{})
+	if !ok && v != nil {
+		err = fmt.Errorf("candid: cannot decode %T as []byte", v)
+		return
+	}
+	r = make([]byte, len(vs4))
+	for i4 := range vs4 {
+		if x, ok := vs4[i4].(*big.Int); ok && x.IsUint64() {
+			r[i4] = uint8(x.Uint64())
+		} else {
+			err = fmt.Errorf("candid: cannot decode %T as uint8", vs4[i4])
+			return
+		}
+	}
+	return
+}
+
+type Tokens = *big.Int
+
+func encodeTokens(v Tokens) (r interface{}, err error) {
+	r = v
+	return
+}
+
+func decodeTokens(v interface{}) (r Tokens, err error) {
+	if x, ok := v.(*big.Int); ok {
+		r = x
+	} else {
+		err = fmt.Errorf("candid: cannot decode %T as *big.Int", v)
+		return
+	}
+	return
+}
+
+type TransferArg struct {
+	To             Account
+	Fee            Tokens
+	Memo           *[]byte
+	FromSubaccount *Subaccount
+	CreatedAtTime  *uint64
+	Amount         Tokens
+}
+
+func encodeTransferArg(v TransferArg) (r interface{}, err error) {
+	rec := make(map[string]interface{}, 6)
+	rec["to"], err = encodeAccount(v.To)
+	if err != nil {
+		return
+	}
+	if v.Fee != nil {
+		rec["fee"], err = encodeTokens(v.Fee)
+		if err != nil {
+			return
+		}
+	}
+	if v.Memo != nil {
+		vs5 := make([]interface{}, len(*v.Memo))
+		for i5 := range vs5 {
+			vs5[i5] = new(big.Int).SetUint64(uint64((*v.Memo)[i5]))
+		}
+		rec["memo"] = vs5
+	}
+	if v.FromSubaccount != nil {
+		rec["from_subaccount"], err = encodeSubaccount(*v.FromSubaccount)
+		if err != nil {
+			return
+		}
+	}
+	if v.CreatedAtTime != nil {
+		rec["created_at_time"] = new(big.Int).SetUint64(uint64(*v.CreatedAtTime))
+	}
+	rec["amount"], err = encodeTokens(v.Amount)
+	if err != nil {
+		return
+	}
+	r = rec
+	return
+}
+
+func decodeTransferArg(v interface{}) (r TransferArg, err error) {
+	rec, ok := v.(map[string]interface{})
+	if !ok && v != nil {
+		err = fmt.Errorf("candid: cannot decode %T as TransferArg", v)
+		return
+	}
+	r.To, err = decodeAccount(rec["to"])
+	if err != nil {
+		return
+	}
+	if rec["fee"] != nil {
+		r.Fee, err = decodeTokens(rec["fee"])
+		if err != nil {
+			return
+		}
+	}
+	if rec["memo"] != nil {
+		var o6 []byte
+		vs7, ok := rec["memo"].([]interface{})
+		if !ok && rec["memo"] != nil {
+			err = fmt.Errorf("candid: cannot decode %T as []byte", rec["memo"])
+			return
+		}
+		o6 = make([]byte, len(vs7))
+		for i7 := range vs7 {
+			if x, ok := vs7[i7].(*big.Int); ok && x.IsUint64() {
+				o6[i7] = uint8(x.Uint64())
+			} else {
+				err = fmt.Errorf("candid: cannot decode %T as uint8", vs7[i7])
+				return
+			}
+		}
+		r.Memo = &o6
+	}
+	if rec["from_subaccount"] != nil {
+		var o8 Subaccount
+		o8, err = decodeSubaccount(rec["from_subaccount"])
+		if err != nil {
+			return
+		}
+		r.FromSubaccount = &o8
+	}
+	if rec["created_at_time"] != nil {
+		var o9 uint64
+		if x, ok := rec["created_at_time"].(*big.Int); ok && x.IsUint64() {
+			o9 = uint64(x.Uint64())
+		} else {
+			err = fmt.Errorf("candid: cannot decode %T as uint64", rec["created_at_time"])
+			return
+		}
+		r.CreatedAtTime = &o9
+	}
+	r.Amount, err = decodeTokens(rec["amount"])
+	if err != nil {
+		return
+	}
+	return
+}
+
+// TransferError is a variant: one of TransferErrorGenericError, TransferErrorBadFee, TransferErrorTooOld, TransferErrorInsufficientFunds.
+type TransferError interface {
+	isTransferError()
+}
+
+type TransferErrorGenericError struct {
+	Message   string
+	ErrorCode *big.Int
+}
+
+func encodeTransferErrorGenericError(v TransferErrorGenericError) (r interface{}, err error) {
+	rec := make(map[string]interface{}, 2)
+	rec["message"] = v.Message
+	rec["error_code"] = v.ErrorCode
+	r = rec
+	return
+}
+
+func decodeTransferErrorGenericError(v interface{}) (r TransferErrorGenericError, err error) {
+	rec, ok := v.(map[string]interface{})
+	if !ok && v != nil {
+		err = fmt.Errorf("candid: cannot decode %T as TransferErrorGenericError", v)
+		return
+	}
+	if x, ok := rec["message"].(string); ok {
+		r.Message = x
+	} else {
+		err = fmt.Errorf("candid: cannot decode %T as string", rec["message"])
+		return
+	}
+	if x, ok := rec["error_code"].(*big.Int); ok {
+		r.ErrorCode = x
+	} else {
+		err = fmt.Errorf("candid: cannot decode %T as *big.Int", rec["error_code"])
+		return
+	}
+	return
+}
+
+type TransferErrorBadFee struct {
+	ExpectedFee Tokens
+}
+
+func encodeTransferErrorBadFee(v TransferErrorBadFee) (r interface{}, err error) {
+	rec := make(map[string]interface{}, 1)
+	rec["expected_fee"], err = encodeTokens(v.ExpectedFee)
+	if err != nil {
+		return
+	}
+	r = rec
+	return
+}
+
+func decodeTransferErrorBadFee(v interface{}) (r TransferErrorBadFee, err error) {
+	rec, ok := v.(map[string]interface{})
+	if !ok && v != nil {
+		err = fmt.Errorf("candid: cannot decode %T as TransferErrorBadFee", v)
+		return
+	}
+	r.ExpectedFee, err = decodeTokens(rec["expected_fee"])
+	if err != nil {
+		return
+	}
+	return
+}
+
+type TransferErrorTooOld struct{}
+
+type TransferErrorInsufficientFunds struct {
+	Balance Tokens
+}
+
+func encodeTransferErrorInsufficientFunds(v TransferErrorInsufficientFunds) (r interface{}, err error) {
+	rec := make(map[string]interface{}, 1)
+	rec["balance"], err = encodeTokens(v.Balance)
+	if err != nil {
+		return
+	}
+	r = rec
+	return
+}
+
+func decodeTransferErrorInsufficientFunds(v interface{}) (r TransferErrorInsufficientFunds, err error) {
+	rec, ok := v.(map[string]interface{})
+	if !ok && v != nil {
+		err = fmt.Errorf("candid: cannot decode %T as TransferErrorInsufficientFunds", v)
+		return
+	}
+	r.Balance, err = decodeTokens(rec["balance"])
+	if err != nil {
+		return
+	}
+	return
+}
+
+func (TransferErrorGenericError) isTransferError()      {}
+func (TransferErrorBadFee) isTransferError()            {}
+func (TransferErrorTooOld) isTransferError()            {}
+func (TransferErrorInsufficientFunds) isTransferError() {}
+
+func encodeTransferError(v TransferError) (r interface{}, err error) {
+	switch c := v.(type) {
+	case TransferErrorGenericError:
+		fv := idl.FieldValue{Name: "GenericError"}
+		fv.Value, err = encodeTransferErrorGenericError(c)
+		if err != nil {
+			return
+		}
+		r = fv
+	case TransferErrorBadFee:
+		fv := idl.FieldValue{Name: "BadFee"}
+		fv.Value, err = encodeTransferErrorBadFee(c)
+		if err != nil {
+			return
+		}
+		r = fv
+	case TransferErrorTooOld:
+		r = idl.FieldValue{Name: "TooOld"}
+	case TransferErrorInsufficientFunds:
+		fv := idl.FieldValue{Name: "InsufficientFunds"}
+		fv.Value, err = encodeTransferErrorInsufficientFunds(c)
+		if err != nil {
+			return
+		}
+		r = fv
+	default:
+		err = fmt.Errorf("candid: cannot encode %T as TransferError", v)
+	}
+	return
+}
+
+func decodeTransferError(v interface{}) (r TransferError, err error) {
+	fv, ok := v.(*idl.FieldValue)
+	if !ok {
+		err = fmt.Errorf("candid: cannot decode %T as TransferError", v)
+		return
+	}
+	switch fv.Name {
+	case "GenericError":
+		var c TransferErrorGenericError
+		c, err = decodeTransferErrorGenericError(fv.Value)
+		if err != nil {
+			return
+		}
+		r = c
+	case "BadFee":
+		var c TransferErrorBadFee
+		c, err = decodeTransferErrorBadFee(fv.Value)
+		if err != nil {
+			return
+		}
+		r = c
+	case "TooOld":
+		r = TransferErrorTooOld{}
+	case "InsufficientFunds":
+		var c TransferErrorInsufficientFunds
+		c, err = decodeTransferErrorInsufficientFunds(fv.Value)
+		if err != nil {
+			return
+		}
+		r = c
+	default:
+		err = fmt.Errorf("candid: unknown case %s of TransferError", fv.Name)
+	}
+	return
+}
+
+// Value is a variant: one of ValueNat, ValueBlob, ValueText, ValueArray.
+type Value interface {
+	isValue()
+}
+
+type ValueNat struct {
+	Value *big.Int
+}
+
+type ValueBlob struct {
+	Value []byte
+}
+
+type ValueText struct {
+	Value string
+}
+
+type ValueArray struct {
+	Value []Value
+}
+
+func (ValueNat) isValue()   {}
+func (ValueBlob) isValue()  {}
+func (ValueText) isValue()  {}
+func (ValueArray) isValue() {}
+
+func encodeValue(v Value) (r interface{}, err error) {
+	switch c := v.(type) {
+	case ValueNat:
+		fv := idl.FieldValue{Name: "Nat"}
+		fv.Value = c.Value
+		r = fv
+	case ValueBlob:
+		fv := idl.FieldValue{Name: "Blob"}
+		vs10 := make([]interface{}, len(c.Value))
+		for i10 := range vs10 {
+			vs10[i10] = new(big.Int).SetUint64(uint64(c.Value[i10]))
+		}
+		fv.Value = vs10
+		r = fv
+	case ValueText:
+		fv := idl.FieldValue{Name: "Text"}
+		fv.Value = c.Value
+		r = fv
+	case ValueArray:
+		fv := idl.FieldValue{Name: "Array"}
+		vs11 := make([]interface{}, len(c.Value))
+		for i11 := range vs11 {
+			vs11[i11], err = encodeValue(c.Value[i11])
+			if err != nil {
+				return
+			}
+		}
+		fv.Value = vs11
+		r = fv
+	default:
+		err = fmt.Errorf("candid: cannot encode %T as Value", v)
+	}
+	return
+}
+
+func decodeValue(v interface{}) (r Value, err error) {
+	fv, ok := v.(*idl.FieldValue)
+	if !ok {
+		err = fmt.Errorf("candid: cannot decode %T as Value", v)
+		return
+	}
+	switch fv.Name {
+	case "Nat":
+		var c ValueNat
+		if x, ok := fv.Value.(*big.Int); ok {
+			c.Value = x
+		} else {
+			err = fmt.Errorf("candid: cannot decode %T as *big.Int", fv.Value)
+			return
+		}
+		r = c
+	case "Blob":
+		var c ValueBlob
+		vs12, ok := fv.Value.([]interface{})
+		if !ok && fv.Value != nil {
+			err = fmt.Errorf("candid: cannot decode %T as []byte", fv.Value)
+			return
+		}
+		c.Value = make([]byte, len(vs12))
+		for i12 := range vs12 {
+			if x, ok := vs12[i12].(*big.Int); ok && x.IsUint64() {
+				c.Value[i12] = uint8(x.Uint64())
+			} else {
+				err = fmt.Errorf("candid: cannot decode %T as uint8", vs12[i12])
+				return
+			}
+		}
+		r = c
+	case "Text":
+		var c ValueText
+		if x, ok := fv.Value.(string); ok {
+			c.Value = x
+		} else {
+			err = fmt.Errorf("candid: cannot decode %T as string", fv.Value)
+			return
+		}
+		r = c
+	case "Array":
+		var c ValueArray
+		vs13, ok := fv.Value.([]interface{})
+		if !ok && fv.Value != nil {
+			err = fmt.Errorf("candid: cannot decode %T as []Value", fv.Value)
+			return
+		}
+		c.Value = make([]Value, len(vs13))
+		for i13 := range vs13 {
+			c.Value[i13], err = decodeValue(vs13[i13])
+			if err != nil {
+				return
+			}
+		}
+		r = c
+	default:
+		err = fmt.Errorf("candid: unknown case %s of Value", fv.Name)
+	}
+	return
+}
+
+type EncodeInitArgsArg struct {
+	MintingAccount Account
+}
+
+func encodeEncodeInitArgsArg(v EncodeInitArgsArg) (r interface{}, err error) {
+	rec := make(map[string]interface{}, 1)
+	rec["minting_account"], err = encodeAccount(v.MintingAccount)
+	if err != nil {
+		return
+	}
+	r = rec
+	return
+}
+
+func decodeEncodeInitArgsArg(v interface{}) (r EncodeInitArgsArg, err error) {
+	rec, ok := v.(map[string]interface{})
+	if !ok && v != nil {
+		err = fmt.Errorf("candid: cannot decode %T as EncodeInitArgsArg", v)
+		return
+	}
+	r.MintingAccount, err = decodeAccount(rec["minting_account"])
+	if err != nil {
+		return
+	}
+	return
+}
+
+type Icrc1MetadataResultItem struct {
+	Field0 string
+	Field1 Value
+}
+
+func encodeIcrc1MetadataResultItem(v Icrc1MetadataResultItem) (r interface{}, err error) {
+	rec := make(map[string]interface{}, 2)
+	rec["0"] = v.Field0
+	rec["1"], err = encodeValue(v.Field1)
+	if err != nil {
+		return
+	}
+	r = rec
+	return
+}
+
+func decodeIcrc1MetadataResultItem(v interface{}) (r Icrc1MetadataResultItem, err error) {
+	rec, ok := v.(map[string]interface{})
+	if !ok && v != nil {
+		err = fmt.Errorf("candid: cannot decode %T as Icrc1MetadataResultItem", v)
+		return
+	}
+	if x, ok := rec["0"].(string); ok {
+		r.Field0 = x
+	} else {
+		err = fmt.Errorf("candid: cannot decode %T as string", rec["0"])
+		return
+	}
+	r.Field1, err = decodeValue(rec["1"])
+	if err != nil {
+		return
+	}
+	return
+}
+
+// Icrc1TransferResult is a variant: one of Icrc1TransferResultOk, Icrc1TransferResultErr.
+type Icrc1TransferResult interface {
+	isIcrc1TransferResult()
+}
+
+type Icrc1TransferResultOk struct {
+	Value Tokens
+}
+
+type Icrc1TransferResultErr struct {
+	Value TransferError
+}
+
+func (Icrc1TransferResultOk) isIcrc1TransferResult()  {}
+func (Icrc1TransferResultErr) isIcrc1TransferResult() {}
+
+func encodeIcrc1TransferResult(v Icrc1TransferResult) (r interface{}, err error) {
+	switch c := v.(type) {
+	case Icrc1TransferResultOk:
+		fv := idl.FieldValue{Name: "Ok"}
+		fv.Value, err = encodeTokens(c.Value)
+		if err != nil {
+			return
+		}
+		r = fv
+	case Icrc1TransferResultErr:
+		fv := idl.FieldValue{Name: "Err"}
+		fv.Value, err = encodeTransferError(c.Value)
+		if err != nil {
+			return
+		}
+		r = fv
+	default:
+		err = fmt.Errorf("candid: cannot encode %T as Icrc1TransferResult", v)
+	}
+	return
+}
+
+func decodeIcrc1TransferResult(v interface{}) (r Icrc1TransferResult, err error) {
+	fv, ok := v.(*idl.FieldValue)
+	if !ok {
+		err = fmt.Errorf("candid: cannot decode %T as Icrc1TransferResult", v)
+		return
+	}
+	switch fv.Name {
+	case "Ok":
+		var c Icrc1TransferResultOk
+		c.Value, err = decodeTokens(fv.Value)
+		if err != nil {
+			return
+		}
+		r = c
+	case "Err":
+		var c Icrc1TransferResultErr
+		c.Value, err = decodeTransferError(fv.Value)
+		if err != nil {
+			return
+		}
+		r = c
+	default:
+		err = fmt.Errorf("candid: unknown case %s of Icrc1TransferResult", fv.Name)
+	}
+	return
+}
+
+// The Candid types of the definitions.
+var (
+	typeAccount       = idl.NewKnot("Account")
+	typeSubaccount    = idl.NewKnot("Subaccount")
+	typeTokens        = idl.NewKnot("Tokens")
+	typeTransferArg   = idl.NewKnot("TransferArg")
+	typeTransferError = idl.NewKnot("TransferError")
+	typeValue         = idl.NewKnot("Value")
+)
+
+func init() {
+	typeAccount.Fill(idl.NewRec(map[string]idl.Type{
+		"owner":      new(idl.Principal),
+		"subaccount": idl.NewOpt(typeSubaccount),
+	}))
+	typeSubaccount.Fill(idl.NewVec(idl.Nat8()))
+	typeTokens.Fill(new(idl.Nat))
+	typeTransferArg.Fill(idl.NewRec(map[string]idl.Type{
+		"to":              typeAccount,
+		"fee":             idl.NewOpt(typeTokens),
+		"memo":            idl.NewOpt(idl.NewVec(idl.Nat8())),
+		"from_subaccount": idl.NewOpt(typeSubaccount),
+		"created_at_time": idl.NewOpt(idl.Nat64()),
+		"amount":          typeTokens,
+	}))
+	typeTransferError.Fill(idl.NewVariant(map[string]idl.Type{
+		"GenericError": idl.NewRec(map[string]idl.Type{
+			"message":    new(idl.Text),
+			"error_code": new(idl.Nat),
+		}),
+		"BadFee": idl.NewRec(map[string]idl.Type{
+			"expected_fee": typeTokens,
+		}),
+		"TooOld": new(idl.Null),
+		"InsufficientFunds": idl.NewRec(map[string]idl.Type{
+			"balance": typeTokens,
+		}),
+	}))
+	typeValue.Fill(idl.NewVariant(map[string]idl.Type{
+		"Nat":   new(idl.Nat),
+		"Blob":  idl.NewVec(idl.Nat8()),
+		"Text":  new(idl.Text),
+		"Array": idl.NewVec(typeValue),
+	}))
+}
+
+// Client is a client of the canister.
+type Client struct {
+	config agent.CallConfig
+}
+
+// NewClient returns a client of the canister that calls it through the agent.
+func NewClient(a agent.Agent, canisterId principal.Principal) *Client {
+	return NewClientWithConfig(agent.CallConfig{Agent: a, CanisterId: canisterId})
+}
+
+// NewClientWithConfig returns a client of the canister that calls it with the configuration.
+func NewClientWithConfig(config agent.CallConfig) *Client {
+	return &Client{config: config}
+}
+
+var funcEncodeInitArgs = idl.NewFunc([]idl.Type{idl.NewRec(map[string]idl.Type{
+	"minting_account": typeAccount,
+})}, []idl.Type{}, nil)
+
+// EncodeInitArgs encodes the arguments of the installation of the canister.
+func EncodeInitArgs(arg0 EncodeInitArgsArg) (arg []byte, err error) {
+	args := make([]interface{}, 1)
+	args[0], err = encodeEncodeInitArgsArg(arg0)
+	if err != nil {
+		return
+	}
+	return idl.Encode(funcEncodeInitArgs.ArgTypes, args)
+}
+
+var funcIcrc1BalanceOf = idl.NewFunc([]idl.Type{typeAccount}, []idl.Type{typeTokens}, []string{"query"})
+
+// Icrc1BalanceOf calls the query method icrc1_balance_of.
+func (c *Client) Icrc1BalanceOf(arg0 Account) (ret0 Tokens, err error) {
+	args := make([]interface{}, 1)
+	args[0], err = encodeAccount(arg0)
+	if err != nil {
+		return
+	}
+	arg, err := idl.Encode(funcIcrc1BalanceOf.ArgTypes, args)
+	if err != nil {
+		return
+	}
+	reply, err := c.config.Query("icrc1_balance_of", arg)
+	if err != nil {
+		return
+	}
+	values, err := idl.DecodeAs(reply, funcIcrc1BalanceOf.RetTypes)
+	if err != nil {
+		return
+	}
+	ret0, err = decodeTokens(values[0])
+	if err != nil {
+		return
+	}
+	return
+}
+
+var funcIcrc1Metadata = idl.NewFunc([]idl.Type{}, []idl.Type{idl.NewVec(idl.NewRec(map[string]idl.Type{
+	"0": new(idl.Text),
+	"1": typeValue,
+}))}, []string{"query"})
+
+// Icrc1Metadata calls the query method icrc1_metadata.
+func (c *Client) Icrc1Metadata() (ret0 []Icrc1MetadataResultItem, err error) {
+	args := make([]interface{}, 0)
+	arg, err := idl.Encode(funcIcrc1Metadata.ArgTypes, args)
+	if err != nil {
+		return
+	}
+	reply, err := c.config.Query("icrc1_metadata", arg)
+	if err != nil {
+		return
+	}
+	values, err := idl.DecodeAs(reply, funcIcrc1Metadata.RetTypes)
+	if err != nil {
+		return
+	}
+	vs14, ok := values[0].([]interface{})
+	if !ok && values[0] != nil {
+		err = fmt.Errorf("candid: cannot decode %T as []Icrc1MetadataResultItem", values[0])
+		return
+	}
+	ret0 = make([]Icrc1MetadataResultItem, len(vs14))
+	for i14 := range vs14 {
+		ret0[i14], err = decodeIcrc1MetadataResultItem(vs14[i14])
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+var funcIcrc1Transfer = idl.NewFunc([]idl.Type{typeTransferArg}, []idl.Type{idl.NewVariant(map[string]idl.Type{
+	"Ok":  typeTokens,
+	"Err": typeTransferError,
+})}, nil)
+
+// Icrc1Transfer calls the update method icrc1_transfer.
+func (c *Client) Icrc1Transfer(arg0 TransferArg) (ret0 Icrc1TransferResult, err error) {
+	args := make([]interface{}, 1)
+	args[0], err = encodeTransferArg(arg0)
+	if err != nil {
+		return
+	}
+	arg, err := idl.Encode(funcIcrc1Transfer.ArgTypes, args)
+	if err != nil {
+		return
+	}
+	reply, err := c.config.Update("icrc1_transfer", arg)
+	if err != nil {
+		return
+	}
+	values, err := idl.DecodeAs(reply, funcIcrc1Transfer.RetTypes)
+	if err != nil {
+		return
+	}
+	ret0, err = decodeIcrc1TransferResult(values[0])
+	if err != nil {
+		return
+	}
+	return
+}
+
+var funcNotify = idl.NewFunc([]idl.Type{new(idl.Nat)}, []idl.Type{}, []string{"oneway"})
+
+// Notify calls the oneway method notify.
+func (c *Client) Notify(arg0 *big.Int) (err error) {
+	args := make([]interface{}, 1)
+	args[0] = arg0
+	arg, err := idl.Encode(funcNotify.ArgTypes, args)
+	if err != nil {
+		return
+	}
+	err = c.config.Oneway("notify", arg)
+	return
+}
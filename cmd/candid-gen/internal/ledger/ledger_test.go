@@ -0,0 +1,156 @@
+package ledger
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/icpfans-xyz/agent-go/agent"
+	"github.com/icpfans-xyz/agent-go/candid/idl"
+	"github.com/icpfans-xyz/agent-go/principal"
+	"github.com/stretchr/testify/assert"
+)
+
+// queryAgent is an agent that answers the queries with a reply.
+type queryAgent struct {
+	agent.Agent
+	method string
+	arg    []byte
+	reply  []byte
+}
+
+func (a *queryAgent) Query(_ *principal.Principal, options *agent.QueryFields) (*agent.QueryResponse, error) {
+	a.method, a.arg = options.MethodName, options.Arg
+	return &agent.QueryResponse{
+		Status: agent.QueryResponseStatusReplied,
+		Reply:  map[string][]byte{"arg": a.reply},
+	}, nil
+}
+
+func TestEncodeInitArgs(t *testing.T) {
+	subaccount := Subaccount{1, 2, 3}
+	arg := EncodeInitArgsArg{MintingAccount: Account{
+		Owner:      principal.Principal{Bytes: []byte{4, 5}},
+		Subaccount: &subaccount,
+	}}
+	bs, err := EncodeInitArgs(arg)
+	assert.NoError(t, err)
+	values, err := idl.DecodeAs(bs, funcEncodeInitArgs.ArgTypes)
+	assert.NoError(t, err)
+	decoded, err := decodeEncodeInitArgsArg(values[0])
+	assert.NoError(t, err)
+	assert.Equal(t, arg, decoded)
+}
+
+func TestTransferArg(t *testing.T) {
+	t.Run("all fields", func(t *testing.T) {
+		memo := []byte("memo")
+		subaccount := Subaccount{7}
+		createdAt := uint64(1 << 40)
+		arg := TransferArg{
+			To:             Account{Owner: principal.Principal{Bytes: []byte{1}}},
+			Fee:            big.NewInt(10),
+			Memo:           &memo,
+			FromSubaccount: &subaccount,
+			CreatedAtTime:  &createdAt,
+			Amount:         big.NewInt(1000),
+		}
+		v, err := encodeTransferArg(arg)
+		assert.NoError(t, err)
+		bs, err := idl.Encode([]idl.Type{typeTransferArg}, []interface{}{v})
+		assert.NoError(t, err)
+		values, err := idl.DecodeAs(bs, []idl.Type{typeTransferArg})
+		assert.NoError(t, err)
+		decoded, err := decodeTransferArg(values[0])
+		assert.NoError(t, err)
+		assert.Equal(t, arg, decoded)
+	})
+
+	t.Run("missing options", func(t *testing.T) {
+		arg := TransferArg{
+			To:     Account{Owner: principal.Principal{Bytes: []byte{1}}},
+			Amount: big.NewInt(1),
+		}
+		v, err := encodeTransferArg(arg)
+		assert.NoError(t, err)
+		bs, err := idl.Encode([]idl.Type{typeTransferArg}, []interface{}{v})
+		assert.NoError(t, err)
+		values, err := idl.DecodeAs(bs, []idl.Type{typeTransferArg})
+		assert.NoError(t, err)
+		decoded, err := decodeTransferArg(values[0])
+		assert.NoError(t, err)
+		assert.Equal(t, arg, decoded)
+	})
+}
+
+func TestClient(t *testing.T) {
+	t.Run("balance", func(t *testing.T) {
+		reply, err := idl.Encode(funcIcrc1BalanceOf.RetTypes, []interface{}{big.NewInt(42)})
+		assert.NoError(t, err)
+		a := &queryAgent{reply: reply}
+		account := Account{Owner: principal.Principal{Bytes: []byte{1, 2}}}
+		balance, err := NewClient(a, principal.Principal{}).Icrc1BalanceOf(account)
+		assert.NoError(t, err)
+		assert.Equal(t, "42", balance.String())
+
+		assert.Equal(t, "icrc1_balance_of", a.method)
+		values, err := idl.DecodeAs(a.arg, funcIcrc1BalanceOf.ArgTypes)
+		assert.NoError(t, err)
+		decoded, err := decodeAccount(values[0])
+		assert.NoError(t, err)
+		assert.Equal(t, account, decoded)
+	})
+
+	t.Run("metadata", func(t *testing.T) {
+		metadata := []Icrc1MetadataResultItem{
+			{Field0: "icrc1:name", Field1: ValueText{Value: "Token"}},
+			{Field0: "icrc1:fee", Field1: ValueNat{Value: big.NewInt(10)}},
+			{Field0: "icrc1:logo", Field1: ValueBlob{Value: []byte{0x89, 'P'}}},
+			{Field0: "nested", Field1: ValueArray{Value: []Value{
+				ValueText{Value: "a"},
+				ValueArray{Value: []Value{}},
+			}}},
+		}
+		var err error
+		items := make([]interface{}, len(metadata))
+		for i, m := range metadata {
+			items[i], err = encodeIcrc1MetadataResultItem(m)
+			assert.NoError(t, err)
+		}
+		reply, err := idl.Encode(funcIcrc1Metadata.RetTypes, []interface{}{items})
+		assert.NoError(t, err)
+		decoded, err := NewClient(&queryAgent{reply: reply}, principal.Principal{}).Icrc1Metadata()
+		assert.NoError(t, err)
+		assert.Equal(t, metadata, decoded)
+	})
+
+	t.Run("variant", func(t *testing.T) {
+		for _, result := range []Icrc1TransferResult{
+			Icrc1TransferResultOk{Value: big.NewInt(3)},
+			Icrc1TransferResultErr{Value: TransferErrorTooOld{}},
+			Icrc1TransferResultErr{Value: TransferErrorBadFee{ExpectedFee: big.NewInt(10)}},
+			Icrc1TransferResultErr{Value: TransferErrorGenericError{Message: "no", ErrorCode: big.NewInt(1)}},
+		} {
+			v, err := encodeIcrc1TransferResult(result)
+			assert.NoError(t, err)
+			bs, err := idl.Encode(funcIcrc1Transfer.RetTypes, []interface{}{v})
+			assert.NoError(t, err)
+			values, err := idl.DecodeAs(bs, funcIcrc1Transfer.RetTypes)
+			assert.NoError(t, err)
+			decoded, err := decodeIcrc1TransferResult(values[0])
+			assert.NoError(t, err)
+			assert.Equal(t, result, decoded)
+		}
+	})
+
+	t.Run("nil variant", func(t *testing.T) {
+		_, err := encodeIcrc1TransferResult(nil)
+		assert.EqualError(t, err, "candid: cannot encode <nil> as Icrc1TransferResult")
+	})
+
+	t.Run("wrong reply", func(t *testing.T) {
+		reply, err := idl.Encode([]idl.Type{new(idl.Text)}, []interface{}{"42"})
+		assert.NoError(t, err)
+		_, err = NewClient(&queryAgent{reply: reply}, principal.Principal{}).Icrc1BalanceOf(Account{})
+		assert.Error(t, err)
+	})
+}
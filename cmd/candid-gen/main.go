@@ -0,0 +1,55 @@
+// Command candid-gen generates Go bindings from a Candid .did file: a Go
+// type for every type definition and a client with one method per method of
+// the service.
+//
+//	candid-gen -package ledger -o ledger.go ledger.did
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/icpfans-xyz/agent-go/candid"
+)
+
+func main() {
+	pkg := flag.String("package", "main", "name of the generated package")
+	client := flag.String("client", "Client", "name of the client type")
+	out := flag.String("o", "", "output file, standard output if empty")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: candid-gen [flags] file.did\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(flag.Arg(0), *out, options{
+		Package: *pkg,
+		Client:  *client,
+		Source:  filepath.Base(flag.Arg(0)),
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "candid-gen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(did, out string, opts options) error {
+	program, err := candid.ParseFile(did)
+	if err != nil {
+		return err
+	}
+	src, err := generate(program, opts)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		_, err := os.Stdout.Write(src)
+		return err
+	}
+	return ioutil.WriteFile(out, src, 0644)
+}
@@ -3,12 +3,16 @@ module github.com/icpfans-xyz/agent-go
 go 1.16
 
 require (
-	github.com/aviate-labs/leb128 v0.3.0 // indirect
+	github.com/aviate-labs/leb128 v0.3.0
 	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.0.1
 	github.com/fxamacker/cbor/v2 v2.4.0
+	github.com/kilic/bls12-381 v0.1.0
 	github.com/kr/pretty v0.2.1 // indirect
 	github.com/mix-labs/IC-Go v0.0.1
 	github.com/stretchr/testify v1.4.0
+	github.com/tyler-smith/go-bip39 v1.1.0
+	golang.org/x/crypto v0.0.0-20220525230936-793ad666bf5e
 	golang.org/x/xerrors v0.0.0-20191204190536-9bdfabe68543
 	gopkg.in/check.v1 v1.0.0-20190902080502-41f04d3bba15 // indirect
 )
@@ -0,0 +1,96 @@
+package identity
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+
+	"github.com/icpfans-xyz/agent-go/principal"
+)
+
+// canisterSigOID is the OID for canister signatures: see
+// https://smartcontracts.org/docs/interface-spec/index.html#canister-signatures.
+var canisterSigOID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 56387, 1, 2}
+
+var errCanisterSigInvalidKey = errors.New("invalid canister signature public key")
+
+// CanisterSigPublicKey is the public key of a canister signature, which
+// identifies the signing canister and the seed it signs for.
+type CanisterSigPublicKey struct {
+	CanisterID *principal.Principal
+	Seed       []byte
+}
+
+func NewCanisterSigPublicKey(canisterId *principal.Principal, seed []byte) *CanisterSigPublicKey {
+	return &CanisterSigPublicKey{
+		CanisterID: canisterId,
+		Seed:       seed,
+	}
+}
+
+// ParseCanisterSigPublicKey decodes a DER-encoded canister signature public
+// key.
+func ParseCanisterSigPublicKey(der []byte) (*CanisterSigPublicKey, error) {
+	var spki subjectPublicKeyInfo
+	if rest, err := asn1.Unmarshal(der, &spki); err != nil {
+		return nil, err
+	} else if len(rest) != 0 {
+		return nil, errCanisterSigInvalidKey
+	}
+	if !spki.Algorithm.Algorithm.Equal(canisterSigOID) {
+		return nil, errCanisterSigInvalidKey
+	}
+	return parseCanisterSigPublicKey(spki.PublicKey.RightAlign())
+}
+
+// IsCanisterSigPublicKey reports whether the DER-encoded public key is a
+// canister signature public key.
+func IsCanisterSigPublicKey(der []byte) bool {
+	var spki subjectPublicKeyInfo
+	if _, err := asn1.Unmarshal(der, &spki); err != nil {
+		return false
+	}
+	return spki.Algorithm.Algorithm.Equal(canisterSigOID)
+}
+
+func parseCanisterSigPublicKey(bytes []byte) (*CanisterSigPublicKey, error) {
+	if len(bytes) == 0 || len(bytes) < 1+int(bytes[0]) {
+		return nil, errCanisterSigInvalidKey
+	}
+	l := int(bytes[0])
+	return &CanisterSigPublicKey{
+		CanisterID: principal.NewPrincipal(append([]byte{}, bytes[1:1+l]...)),
+		Seed:       append([]byte{}, bytes[1+l:]...),
+	}, nil
+}
+
+// ToBytes returns the length of the canister id, the canister id and the
+// seed.
+func (p *CanisterSigPublicKey) ToBytes() []byte {
+	canisterId := p.CanisterID.ToBytes()
+	bytes := append([]byte{byte(len(canisterId))}, canisterId...)
+	return append(bytes, p.Seed...)
+}
+
+// Verify always fails: canister signatures can only be checked against a
+// certificate of the IC, see agent.VerifyCanisterSignature.
+func (p *CanisterSigPublicKey) Verify(message []byte, signature []byte) bool {
+	return false
+}
+
+func (p *CanisterSigPublicKey) ToDer() []byte {
+	bytes := p.ToBytes()
+	der, err := asn1.Marshal(subjectPublicKeyInfo{
+		Algorithm: pkix.AlgorithmIdentifier{
+			Algorithm: canisterSigOID,
+		},
+		PublicKey: asn1.BitString{
+			BitLength: len(bytes) * 8,
+			Bytes:     bytes,
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+	return der
+}
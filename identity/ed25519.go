@@ -38,7 +38,11 @@ func (t *Ed25519Identity) PublicKey() PublicKey {
 }
 
 func (p *Ed25519PublicKey) ToBytes() []byte {
-	return p.pub.([]byte)
+	return p.pub.(ed25519.PublicKey)
+}
+
+func (p *Ed25519PublicKey) Verify(message []byte, signature []byte) bool {
+	return ed25519.Verify(p.pub.(ed25519.PublicKey), message, signature)
 }
 
 func (p *Ed25519PublicKey) ToDer() []byte {
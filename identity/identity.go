@@ -6,6 +6,9 @@ type PublicKey interface {
 
 	// Get the public key bytes encoded with DER.
 	ToDer() []byte
+
+	// Verify reports whether the signature of the message is valid.
+	Verify(message []byte, signature []byte) bool
 }
 
 /**
@@ -0,0 +1,232 @@
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/icpfans-xyz/agent-go/identity"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Version of the encrypted key file format.
+const Version = 1
+
+type KDF string
+
+const (
+	KDFScrypt   KDF = "scrypt"
+	KDFArgon2id KDF = "argon2id"
+)
+
+const CipherAES256GCM = "aes-256-gcm"
+
+const (
+	keyLength  = 32
+	saltLength = 32
+)
+
+// Default key derivation parameters.
+const (
+	DefaultScryptN = 1 << 17
+	DefaultScryptR = 8
+	DefaultScryptP = 1
+
+	DefaultArgon2Time    = 3
+	DefaultArgon2Memory  = 64 * 1024
+	DefaultArgon2Threads = 4
+)
+
+// Maximum key derivation parameters, which bound the memory and time used
+// to decrypt a key file that could have been crafted: scrypt uses 128*N*R
+// bytes of memory and argon2id Memory KiB.
+const (
+	MaxScryptMemory = 1 << 30
+	MaxScryptP      = 16
+
+	MaxArgon2Time   = 16
+	MaxArgon2Memory = 1 << 20
+)
+
+var (
+	ErrWrongPassphrase    = errors.New("could not decrypt key with given passphrase")
+	ErrUnsupportedVersion = errors.New("unsupported keystore version")
+	ErrKDFParams          = errors.New("key derivation parameters out of bounds")
+)
+
+// KDFParams holds the key derivation parameters. Only the fields of the
+// selected KDF are used; zero values are replaced by the defaults.
+type KDFParams struct {
+	Salt string `json:"salt"`
+
+	// scrypt
+	N int `json:"n,omitempty"`
+	R int `json:"r,omitempty"`
+	P int `json:"p,omitempty"`
+
+	// argon2id
+	Time    uint32 `json:"time,omitempty"`
+	Memory  uint32 `json:"memory,omitempty"`
+	Threads uint8  `json:"threads,omitempty"`
+}
+
+type CryptoJSON struct {
+	KDF        KDF       `json:"kdf"`
+	KDFParams  KDFParams `json:"kdfparams"`
+	Cipher     string    `json:"cipher"`
+	Nonce      string    `json:"nonce"`
+	Ciphertext string    `json:"ciphertext"`
+}
+
+// EncryptedKey is the JSON representation of a key file. The plaintext is
+// the PEM encoding of the key pair; the public key is kept in the clear so
+// that identities can be listed without the passphrase.
+type EncryptedKey struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	PublicKey string     `json:"public_key"`
+	Crypto    CryptoJSON `json:"crypto"`
+}
+
+// EncryptKey encrypts the key pair with a key derived from the passphrase.
+func EncryptKey(name string, key identity.KeyPair, passphrase string, kdf KDF, params KDFParams) (*EncryptedKey, error) {
+	plaintext, err := identity.ToPem(key)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	params.Salt = hex.EncodeToString(salt)
+	if kdf == "" {
+		kdf = KDFScrypt
+	}
+	params = params.withDefaults(kdf)
+	derived, err := deriveKey(passphrase, kdf, params)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(derived)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	ek := &EncryptedKey{
+		Version:   Version,
+		Name:      name,
+		PublicKey: hex.EncodeToString(key.PublicKey().ToDer()),
+		Crypto: CryptoJSON{
+			KDF:       kdf,
+			KDFParams: params,
+			Cipher:    CipherAES256GCM,
+			Nonce:     hex.EncodeToString(nonce),
+		},
+	}
+	ciphertext := aead.Seal(nil, nonce, plaintext, ek.additionalData())
+	ek.Crypto.Ciphertext = hex.EncodeToString(ciphertext)
+	return ek, nil
+}
+
+// DecryptKey decrypts the key pair with a key derived from the passphrase.
+func DecryptKey(ek *EncryptedKey, passphrase string) (identity.KeyPair, error) {
+	if ek.Version != Version {
+		return nil, ErrUnsupportedVersion
+	}
+	if ek.Crypto.Cipher != CipherAES256GCM {
+		return nil, fmt.Errorf("unsupported cipher: %s", ek.Crypto.Cipher)
+	}
+	nonce, err := hex.DecodeString(ek.Crypto.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := hex.DecodeString(ek.Crypto.Ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	derived, err := deriveKey(passphrase, ek.Crypto.KDF, ek.Crypto.KDFParams)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(derived)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce length: %d", len(nonce))
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext, ek.additionalData())
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	return identity.FromPem(plaintext)
+}
+
+// additionalData binds the unencrypted header fields to the ciphertext.
+func (ek *EncryptedKey) additionalData() []byte {
+	return []byte(fmt.Sprintf("%d\x00%s\x00%s", ek.Version, ek.Name, ek.PublicKey))
+}
+
+func (p KDFParams) withDefaults(kdf KDF) KDFParams {
+	switch kdf {
+	case KDFScrypt:
+		if p.N == 0 {
+			p.N = DefaultScryptN
+		}
+		if p.R == 0 {
+			p.R = DefaultScryptR
+		}
+		if p.P == 0 {
+			p.P = DefaultScryptP
+		}
+	case KDFArgon2id:
+		if p.Time == 0 {
+			p.Time = DefaultArgon2Time
+		}
+		if p.Memory == 0 {
+			p.Memory = DefaultArgon2Memory
+		}
+		if p.Threads == 0 {
+			p.Threads = DefaultArgon2Threads
+		}
+	}
+	return p
+}
+
+func deriveKey(passphrase string, kdf KDF, params KDFParams) ([]byte, error) {
+	salt, err := hex.DecodeString(params.Salt)
+	if err != nil {
+		return nil, err
+	}
+	switch kdf {
+	case KDFScrypt:
+		if params.N <= 1 || params.R <= 0 || params.P <= 0 || params.P > MaxScryptP ||
+			params.R > MaxScryptMemory/128 || params.N > MaxScryptMemory/128/params.R {
+			return nil, ErrKDFParams
+		}
+		return scrypt.Key([]byte(passphrase), salt, params.N, params.R, params.P, keyLength)
+	case KDFArgon2id:
+		if params.Time == 0 || params.Memory == 0 || params.Threads == 0 ||
+			params.Time > MaxArgon2Time || params.Memory > MaxArgon2Memory {
+			return nil, ErrKDFParams
+		}
+		return argon2.IDKey([]byte(passphrase), salt, params.Time, params.Memory, params.Threads, keyLength), nil
+	default:
+		return nil, fmt.Errorf("unsupported kdf: %s", kdf)
+	}
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,188 @@
+package keystore
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/icpfans-xyz/agent-go/identity"
+)
+
+const fileExtension = ".json"
+
+var (
+	ErrNotFound    = errors.New("identity not found")
+	ErrExists      = errors.New("identity already exists")
+	ErrInvalidName = errors.New("invalid identity name")
+)
+
+var nameRegexp = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]*$`)
+
+// Keystore options that can be used at construction.
+type KeystoreOptions struct {
+	// The key derivation function for newly stored keys. Defaults to scrypt.
+	KDF KDF
+
+	// Key derivation parameters for newly stored keys. Zero values are
+	// replaced by the defaults.
+	KDFParams KDFParams
+}
+
+// Keystore stores passphrase-encrypted identities as one JSON file per name
+// in a directory.
+type Keystore struct {
+	dir string
+
+	kdf KDF
+
+	kdfParams KDFParams
+}
+
+func NewKeystore(dir string, options KeystoreOptions) (*Keystore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	ks := &Keystore{
+		dir:       dir,
+		kdf:       options.KDF,
+		kdfParams: options.KDFParams,
+	}
+	if ks.kdf == "" {
+		ks.kdf = KDFScrypt
+	}
+	return ks, nil
+}
+
+// Store encrypts the key pair with the passphrase and saves it by name. It
+// fails with ErrExists if an identity with that name is already stored.
+func (k *Keystore) Store(name string, key identity.KeyPair, passphrase string) error {
+	path, err := k.path(name)
+	if err != nil {
+		return err
+	}
+	// Avoid deriving a key for nothing. The file is still created without
+	// replacing an existing one below, in case of a concurrent Store.
+	if _, err := os.Stat(path); err == nil {
+		return ErrExists
+	}
+	ek, err := EncryptKey(name, key, passphrase, k.kdf, k.kdfParams)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(ek, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(k.dir, "."+name+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// Unlike a rename, a link fails if the file exists, so that the complete
+	// file appears at once and never overwrites another identity.
+	if err := os.Link(tmp.Name(), path); err != nil {
+		if os.IsExist(err) {
+			return ErrExists
+		}
+		return err
+	}
+	return nil
+}
+
+// Load reads the identity with the given name and decrypts it.
+func (k *Keystore) Load(name string, passphrase string) (identity.KeyPair, error) {
+	ek, err := k.read(name)
+	if err != nil {
+		return nil, err
+	}
+	return DecryptKey(ek, passphrase)
+}
+
+// PublicKey returns the DER-encoded public key of a stored identity without
+// decrypting it.
+func (k *Keystore) PublicKey(name string) ([]byte, error) {
+	ek, err := k.read(name)
+	if err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(ek.PublicKey)
+}
+
+// List returns the names of all stored identities in sorted order.
+func (k *Keystore) List() ([]string, error) {
+	entries, err := ioutil.ReadDir(k.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, fileExtension) {
+			continue
+		}
+		name = strings.TrimSuffix(name, fileExtension)
+		if nameRegexp.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Delete removes the identity with the given name.
+func (k *Keystore) Delete(name string) error {
+	path, err := k.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (k *Keystore) read(name string) (*EncryptedKey, error) {
+	path, err := k.path(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	var ek EncryptedKey
+	if err := json.Unmarshal(data, &ek); err != nil {
+		return nil, fmt.Errorf("invalid key file %s: %v", path, err)
+	}
+	if ek.Name != name {
+		return nil, fmt.Errorf("key file %s belongs to %q", path, ek.Name)
+	}
+	return &ek, nil
+}
+
+func (k *Keystore) path(name string) (string, error) {
+	if !nameRegexp.MatchString(name) {
+		return "", ErrInvalidName
+	}
+	return filepath.Join(k.dir, name+fileExtension), nil
+}
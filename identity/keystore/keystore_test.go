@@ -0,0 +1,134 @@
+package keystore_test
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/icpfans-xyz/agent-go/identity"
+	"github.com/icpfans-xyz/agent-go/identity/keystore"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupKeystore(t *testing.T, kdf keystore.KDF) (*keystore.Keystore, string) {
+	dir, err := ioutil.TempDir("", "keystore")
+	assert.Nil(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	ks, err := keystore.NewKeystore(dir, keystore.KeystoreOptions{
+		KDF: kdf,
+		KDFParams: keystore.KDFParams{
+			N: 1 << 10, Time: 1, Memory: 1024, Threads: 1,
+		},
+	})
+	assert.Nil(t, err)
+	return ks, dir
+}
+
+func TestKeystore(t *testing.T) {
+	seed, _ := hex.DecodeString("833fe62409237b9d62ec77587520911e9a759cec1d19755b7da901b96dca3d42")
+	ed := identity.NewEd25519Identity(seed)
+	k1, err := identity.GenerateSecp256k1Identity()
+	assert.Nil(t, err)
+
+	for _, kdf := range []keystore.KDF{keystore.KDFScrypt, keystore.KDFArgon2id} {
+		t.Run(string(kdf), func(t *testing.T) {
+			ks, dir := setupKeystore(t, kdf)
+
+			assert.Nil(t, ks.Store("ops", ed, "hunter2"))
+			assert.Nil(t, ks.Store("k1", k1, "hunter2"))
+			assert.Equal(t, keystore.ErrExists, ks.Store("ops", ed, "hunter2"))
+			assert.Equal(t, keystore.ErrInvalidName, ks.Store("../ops", ed, "hunter2"))
+
+			names, err := ks.List()
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"k1", "ops"}, names)
+
+			key, err := ks.Load("ops", "hunter2")
+			assert.Nil(t, err)
+			assert.Equal(t, seed, key.SecretKey())
+			key, err = ks.Load("k1", "hunter2")
+			assert.Nil(t, err)
+			assert.Equal(t, k1.SecretKey(), key.SecretKey())
+
+			pub, err := ks.PublicKey("ops")
+			assert.Nil(t, err)
+			assert.Equal(t, ed.PublicKey().ToDer(), pub)
+
+			_, err = ks.Load("ops", "wrong")
+			assert.Equal(t, keystore.ErrWrongPassphrase, err)
+
+			data, err := ioutil.ReadFile(filepath.Join(dir, "ops.json"))
+			assert.Nil(t, err)
+			assert.NotContains(t, string(data), hex.EncodeToString(seed))
+
+			assert.Nil(t, ks.Delete("ops"))
+			assert.Equal(t, keystore.ErrNotFound, ks.Delete("ops"))
+			_, err = ks.Load("ops", "hunter2")
+			assert.Equal(t, keystore.ErrNotFound, err)
+		})
+	}
+}
+
+func TestDecryptKeyTampered(t *testing.T) {
+	seed, _ := hex.DecodeString("833fe62409237b9d62ec77587520911e9a759cec1d19755b7da901b96dca3d42")
+	ek, err := keystore.EncryptKey("ops", identity.NewEd25519Identity(seed), "hunter2", keystore.KDFScrypt, keystore.KDFParams{N: 1 << 10})
+	assert.Nil(t, err)
+
+	ek.Name = "other"
+	_, err = keystore.DecryptKey(ek, "hunter2")
+	assert.Equal(t, keystore.ErrWrongPassphrase, err)
+
+	ek.Name = "ops"
+	ek.Version = 2
+	_, err = keystore.DecryptKey(ek, "hunter2")
+	assert.Equal(t, keystore.ErrUnsupportedVersion, err)
+}
+
+func TestDecryptKeyParams(t *testing.T) {
+	seed, _ := hex.DecodeString("833fe62409237b9d62ec77587520911e9a759cec1d19755b7da901b96dca3d42")
+	ek, err := keystore.EncryptKey("ops", identity.NewEd25519Identity(seed), "hunter2", keystore.KDFScrypt, keystore.KDFParams{N: 1 << 10})
+	assert.Nil(t, err)
+
+	// A crafted file must not make decryption use gigabytes of memory.
+	for _, params := range []keystore.KDFParams{
+		{N: 1 << 30, R: 8, P: 1},
+		{N: 1 << 10, R: 1 << 30, P: 1},
+		{N: 1 << 10, R: 8, P: 1 << 20},
+		{N: 0, R: 8, P: 1},
+	} {
+		params.Salt = ek.Crypto.KDFParams.Salt
+		ek.Crypto.KDFParams = params
+		_, err = keystore.DecryptKey(ek, "hunter2")
+		assert.Equal(t, keystore.ErrKDFParams, err)
+	}
+
+	ek.Crypto.KDF = keystore.KDFArgon2id
+	for _, params := range []keystore.KDFParams{
+		{Time: 1, Memory: 1 << 30, Threads: 1},
+		{Time: 1 << 30, Memory: 1024, Threads: 1},
+	} {
+		ek.Crypto.KDFParams = params
+		_, err = keystore.DecryptKey(ek, "hunter2")
+		assert.Equal(t, keystore.ErrKDFParams, err)
+	}
+}
+
+func TestStoreDoesNotOverwrite(t *testing.T) {
+	seed, _ := hex.DecodeString("833fe62409237b9d62ec77587520911e9a759cec1d19755b7da901b96dca3d42")
+	ks, dir := setupKeystore(t, keystore.KDFScrypt)
+	path := filepath.Join(dir, "ops.json")
+
+	// An existing file is left as is.
+	assert.Nil(t, ioutil.WriteFile(path, []byte("{}"), 0600))
+	assert.Equal(t, keystore.ErrExists, ks.Store("ops", identity.NewEd25519Identity(seed), "hunter2"))
+	data, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "{}", string(data))
+
+	// No temporary file is left behind.
+	entries, err := ioutil.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+}
@@ -0,0 +1,147 @@
+package identity
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// ICPDerivationPath is the BIP44 account path for ICP (coin type 223). The
+// address index is appended to it.
+const ICPDerivationPath = "m/44'/223'/0'/0"
+
+// Index offset of hardened BIP32 children.
+const hardenedOffset uint32 = 0x80000000
+
+var (
+	errBip32InvalidPath = errors.New("invalid derivation path")
+	errBip32InvalidKey  = errors.New("derived key is invalid")
+)
+
+// NewMnemonic generates a random BIP39 mnemonic with the given entropy size
+// in bits (128 for 12 words up to 256 for 24 words).
+func NewMnemonic(bitSize int) (string, error) {
+	entropy, err := bip39.NewEntropy(bitSize)
+	if err != nil {
+		return "", err
+	}
+	return bip39.NewMnemonic(entropy)
+}
+
+// MnemonicToSeed validates the mnemonic and returns its 64-byte BIP39 seed.
+func MnemonicToSeed(mnemonic string, passphrase string) ([]byte, error) {
+	return bip39.NewSeedWithErrorChecking(normalizeMnemonic(mnemonic), passphrase)
+}
+
+// NewEd25519IdentityFromMnemonic creates an identity whose Ed25519 seed is
+// the first 32 bytes of the BIP39 seed of the mnemonic.
+//
+// This derivation is specific to this package: it is neither BIP32 nor
+// SLIP-0010, so other wallets give a different key for the same mnemonic.
+// Use NewSecp256k1IdentityFromMnemonic for identities shared with keysmith
+// or dfx.
+func NewEd25519IdentityFromMnemonic(mnemonic string, passphrase string) (*Ed25519Identity, error) {
+	seed, err := MnemonicToSeed(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return NewEd25519Identity(seed[:ed25519.SeedSize]), nil
+}
+
+// NewSecp256k1IdentityFromMnemonic creates the identity at the given address
+// index of the ICP derivation path m/44'/223'/0'/0/<index>.
+func NewSecp256k1IdentityFromMnemonic(mnemonic string, passphrase string, index uint32) (*Secp256k1Identity, error) {
+	seed, err := MnemonicToSeed(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return DeriveSecp256k1Identity(seed, fmt.Sprintf("%s/%d", ICPDerivationPath, index))
+}
+
+// DeriveSecp256k1Identity derives a secp256k1 identity from a BIP32 seed
+// along a path such as "m/44'/223'/0'/0/0".
+func DeriveSecp256k1Identity(seed []byte, path string) (*Secp256k1Identity, error) {
+	indexes, err := parseDerivationPath(path)
+	if err != nil {
+		return nil, err
+	}
+	key, chainCode, err := bip32Derive([]byte("Bitcoin seed"), seed)
+	if err != nil {
+		return nil, err
+	}
+	for _, index := range indexes {
+		key, chainCode, err = bip32Child(key, chainCode, index)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return newSecp256k1Identity(secp256k1.NewPrivateKey(key)), nil
+}
+
+func normalizeMnemonic(mnemonic string) string {
+	return strings.Join(strings.Fields(strings.ToLower(mnemonic)), " ")
+}
+
+func parseDerivationPath(path string) ([]uint32, error) {
+	segments := strings.Split(path, "/")
+	if segments[0] != "m" {
+		return nil, errBip32InvalidPath
+	}
+	var indexes []uint32
+	for _, s := range segments[1:] {
+		var offset uint32
+		if strings.HasSuffix(s, "'") || strings.HasSuffix(s, "h") || strings.HasSuffix(s, "H") {
+			offset = hardenedOffset
+			s = s[:len(s)-1]
+		}
+		i, err := strconv.ParseUint(s, 10, 32)
+		if err != nil || uint32(i) >= hardenedOffset {
+			return nil, errBip32InvalidPath
+		}
+		indexes = append(indexes, uint32(i)+offset)
+	}
+	return indexes, nil
+}
+
+// bip32Derive computes HMAC-SHA512(key, data) and splits it into a private
+// key and a chain code.
+func bip32Derive(key []byte, data []byte) (*secp256k1.ModNScalar, []byte, error) {
+	mac := hmac.New(sha512.New, key)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+	var k secp256k1.ModNScalar
+	if k.SetByteSlice(sum[:32]) || k.IsZero() {
+		return nil, nil, errBip32InvalidKey
+	}
+	return &k, sum[32:], nil
+}
+
+func bip32Child(key *secp256k1.ModNScalar, chainCode []byte, index uint32) (*secp256k1.ModNScalar, []byte, error) {
+	var data []byte
+	if index >= hardenedOffset {
+		k := key.Bytes()
+		data = append([]byte{0x00}, k[:]...)
+	} else {
+		data = secp256k1.NewPrivateKey(key).PubKey().SerializeCompressed()
+	}
+	data = append(data, make([]byte, 4)...)
+	binary.BigEndian.PutUint32(data[len(data)-4:], index)
+
+	child, childChainCode, err := bip32Derive(chainCode, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	child.Add(key)
+	if child.IsZero() {
+		return nil, nil, errBip32InvalidKey
+	}
+	return child, childChainCode, nil
+}
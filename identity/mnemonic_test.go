@@ -0,0 +1,70 @@
+package identity_test
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/icpfans-xyz/agent-go/identity"
+	"github.com/icpfans-xyz/agent-go/principal"
+	"github.com/stretchr/testify/assert"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+func TestMnemonicToSeed(t *testing.T) {
+	seed, err := identity.MnemonicToSeed(testMnemonic, "TREZOR")
+	assert.Nil(t, err)
+	assert.Equal(t, "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04", hex.EncodeToString(seed))
+
+	_, err = identity.MnemonicToSeed(strings.Replace(testMnemonic, "about", "abandon", 1), "")
+	assert.NotNil(t, err)
+
+	id, err := identity.NewEd25519IdentityFromMnemonic(strings.ToUpper(testMnemonic), "TREZOR")
+	assert.Nil(t, err)
+	assert.Equal(t, seed[:32], id.SecretKey())
+}
+
+func TestDeriveSecp256k1Identity(t *testing.T) {
+	// Test vector 1 from BIP32.
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	for path, key := range map[string]string{
+		"m":         "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35",
+		"m/0'":      "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea",
+		"m/0'/1":    "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368",
+		"m/0H/1/2h": "cbce0d719ecf7431d88e6a89fa1483e02e35092af60c042b1df2ff59fa424dca",
+	} {
+		id, err := identity.DeriveSecp256k1Identity(seed, path)
+		assert.Nil(t, err, path)
+		assert.Equal(t, key, hex.EncodeToString(id.SecretKey()), path)
+	}
+
+	_, err := identity.DeriveSecp256k1Identity(seed, "0/1")
+	assert.NotNil(t, err)
+	_, err = identity.DeriveSecp256k1Identity(seed, "m/2147483648")
+	assert.NotNil(t, err)
+}
+
+func TestNewSecp256k1IdentityFromMnemonic(t *testing.T) {
+	// The key of the ICP path m/44'/223'/0'/0/0, as used by keysmith and
+	// dfx, checked against an independent BIP32 implementation.
+	id0, err := identity.NewSecp256k1IdentityFromMnemonic(testMnemonic, "", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "f60151c409cb357e00a4267ad2cfa0001ff431ef5911110d651b1e7fc03451ac", hex.EncodeToString(id0.SecretKey()))
+	p, err := principal.SelfAuthenticating(id0.PublicKey().ToDer())
+	assert.Nil(t, err)
+	assert.Equal(t, "tgzar-4lpln-fq34h-6hxo4-wlm3x-6g3or-6hxvr-d6jbw-ooh2b-lzsw4-aqe", p.ToString())
+
+	seed, err := identity.MnemonicToSeed(testMnemonic, "")
+	assert.Nil(t, err)
+	expected, err := identity.DeriveSecp256k1Identity(seed, "m/44'/223'/0'/0/1")
+	assert.Nil(t, err)
+
+	id, err := identity.NewSecp256k1IdentityFromMnemonic(testMnemonic, "", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, expected.SecretKey(), id.SecretKey())
+
+	mnemonic, err := identity.NewMnemonic(256)
+	assert.Nil(t, err)
+	assert.Len(t, strings.Fields(mnemonic), 24)
+}
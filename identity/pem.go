@@ -0,0 +1,227 @@
+package identity
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+const (
+	pemTypePrivateKey   = "PRIVATE KEY"
+	pemTypeECPrivateKey = "EC PRIVATE KEY"
+	pemTypeECParameters = "EC PARAMETERS"
+)
+
+var (
+	errPemNoKey            = errors.New("no private key found in PEM data")
+	errPemUnknownAlgorithm = errors.New("unsupported private key algorithm")
+	errPemUnknownCurve     = errors.New("unsupported elliptic curve")
+	errPemPublicKey        = errors.New("public key does not match private key")
+)
+
+// pkcs8 reflects the OneAsymmetricKey ASN.1 structure from RFC 5958. Unlike
+// the structure used by crypto/x509 it keeps the optional public key, which
+// legacy dfx Ed25519 identities include (as an explicitly tagged field).
+type pkcs8 struct {
+	Version    int
+	Algo       pkix.AlgorithmIdentifier
+	PrivateKey []byte
+	Attributes asn1.RawValue `asn1:"optional,tag:0"`
+	PublicKey  asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+// ecPrivateKey reflects the ECPrivateKey ASN.1 structure from RFC 5915.
+type ecPrivateKey struct {
+	Version       int
+	PrivateKey    []byte
+	NamedCurveOID asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+	PublicKey     asn1.BitString        `asn1:"optional,explicit,tag:1"`
+}
+
+// DfxIdentityPath returns the location of the PEM file dfx uses for the
+// identity with the given name.
+func DfxIdentityPath(name string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "dfx", "identity", name, "identity.pem"), nil
+}
+
+// LoadDfxIdentity loads the dfx identity with the given name.
+func LoadDfxIdentity(name string) (KeyPair, error) {
+	path, err := DfxIdentityPath(name)
+	if err != nil {
+		return nil, err
+	}
+	return FromPemFile(path)
+}
+
+// FromPemFile reads a private key from a PEM file.
+func FromPemFile(path string) (KeyPair, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return FromPem(data)
+}
+
+// FromPem parses the first private key in the PEM data. Ed25519 keys are
+// expected in PKCS#8 form, ECDSA keys (secp256k1 and prime256v1) either in
+// PKCS#8 or SEC1 form. Leading "EC PARAMETERS" blocks are skipped.
+func FromPem(data []byte) (KeyPair, error) {
+	for {
+		block, rest := pem.Decode(data)
+		if block == nil {
+			return nil, errPemNoKey
+		}
+		switch block.Type {
+		case pemTypePrivateKey:
+			return parsePKCS8PrivateKey(block.Bytes)
+		case pemTypeECPrivateKey:
+			return parseECPrivateKey(nil, block.Bytes)
+		}
+		data = rest
+	}
+}
+
+// ToPem encodes the key pair as PEM: Ed25519 keys in PKCS#8 form and ECDSA
+// keys in SEC1 form, matching the files written by dfx.
+func ToPem(key KeyPair) ([]byte, error) {
+	var block *pem.Block
+	switch k := key.(type) {
+	case *Ed25519Identity:
+		der, err := marshalEd25519PrivateKey(k)
+		if err != nil {
+			return nil, err
+		}
+		block = &pem.Block{Type: pemTypePrivateKey, Bytes: der}
+	case *Secp256k1Identity:
+		der, err := marshalECPrivateKey(secp256k1OID, k.SecretKey(), k.PublicKey().ToBytes())
+		if err != nil {
+			return nil, err
+		}
+		block = &pem.Block{Type: pemTypeECPrivateKey, Bytes: der}
+	case *Prime256v1Identity:
+		der, err := marshalECPrivateKey(prime256v1OID, k.SecretKey(), k.PublicKey().ToBytes())
+		if err != nil {
+			return nil, err
+		}
+		block = &pem.Block{Type: pemTypeECPrivateKey, Bytes: der}
+	default:
+		return nil, fmt.Errorf("cannot encode %T as PEM", key)
+	}
+	return pem.EncodeToMemory(block), nil
+}
+
+func parsePKCS8PrivateKey(der []byte) (KeyPair, error) {
+	var key pkcs8
+	if _, err := asn1.Unmarshal(der, &key); err != nil {
+		return nil, err
+	}
+	switch {
+	case key.Algo.Algorithm.Equal(ed25519OID):
+		var seed []byte
+		if _, err := asn1.Unmarshal(key.PrivateKey, &seed); err != nil {
+			return nil, err
+		}
+		if len(seed) != ed25519.SeedSize {
+			return nil, fmt.Errorf("invalid ed25519 private key length: %d", len(seed))
+		}
+		id := NewEd25519Identity(seed)
+		if len(key.PublicKey.FullBytes) != 0 {
+			pub, err := parsePKCS8PublicKey(key.PublicKey)
+			if err != nil {
+				return nil, err
+			}
+			if !bytes.Equal(pub, id.PublicKey().ToBytes()) {
+				return nil, errPemPublicKey
+			}
+		}
+		return id, nil
+	case key.Algo.Algorithm.Equal(ecPublicKeyOID):
+		var curve asn1.ObjectIdentifier
+		if _, err := asn1.Unmarshal(key.Algo.Parameters.FullBytes, &curve); err != nil {
+			return nil, err
+		}
+		return parseECPrivateKey(curve, key.PrivateKey)
+	default:
+		return nil, errPemUnknownAlgorithm
+	}
+}
+
+// parsePKCS8PublicKey accepts both the implicitly tagged bit string from
+// RFC 5958 and the explicitly tagged form written by older dfx versions.
+func parsePKCS8PublicKey(raw asn1.RawValue) ([]byte, error) {
+	if raw.IsCompound {
+		var pub asn1.BitString
+		if _, err := asn1.Unmarshal(raw.Bytes, &pub); err != nil {
+			return nil, err
+		}
+		return pub.RightAlign(), nil
+	}
+	if len(raw.Bytes) == 0 || raw.Bytes[0] != 0 {
+		return nil, errors.New("invalid public key bit string")
+	}
+	return raw.Bytes[1:], nil
+}
+
+func parseECPrivateKey(curve asn1.ObjectIdentifier, der []byte) (KeyPair, error) {
+	var key ecPrivateKey
+	if _, err := asn1.Unmarshal(der, &key); err != nil {
+		return nil, err
+	}
+	if key.Version != 1 {
+		return nil, fmt.Errorf("unknown EC private key version: %d", key.Version)
+	}
+	if len(key.NamedCurveOID) != 0 {
+		curve = key.NamedCurveOID
+	}
+	var id KeyPair
+	var err error
+	switch {
+	case curve.Equal(secp256k1OID):
+		id, err = NewSecp256k1Identity(key.PrivateKey)
+	case curve.Equal(prime256v1OID):
+		id, err = NewPrime256v1Identity(key.PrivateKey)
+	default:
+		return nil, errPemUnknownCurve
+	}
+	if err != nil {
+		return nil, err
+	}
+	if key.PublicKey.BitLength != 0 && !bytes.Equal(key.PublicKey.RightAlign(), id.PublicKey().ToBytes()) {
+		return nil, errPemPublicKey
+	}
+	return id, nil
+}
+
+func marshalEd25519PrivateKey(id *Ed25519Identity) ([]byte, error) {
+	seed, err := asn1.Marshal(id.SecretKey())
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(pkcs8{
+		Algo:       pkix.AlgorithmIdentifier{Algorithm: ed25519OID},
+		PrivateKey: seed,
+	})
+}
+
+func marshalECPrivateKey(curve asn1.ObjectIdentifier, secret, point []byte) ([]byte, error) {
+	return asn1.Marshal(ecPrivateKey{
+		Version:       1,
+		PrivateKey:    secret,
+		NamedCurveOID: curve,
+		PublicKey: asn1.BitString{
+			BitLength: len(point) * 8,
+			Bytes:     point,
+		},
+	})
+}
@@ -0,0 +1,110 @@
+package identity
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"errors"
+	"math/big"
+)
+
+// Length of the raw private scalar and of each signature component.
+const prime256v1ScalarLength = 32
+
+var errPrime256v1InvalidKey = errors.New("invalid prime256v1 private key")
+
+type Prime256v1PublicKey struct {
+	pub *ecdsa.PublicKey
+}
+
+type Prime256v1Identity struct {
+	PriKey *ecdsa.PrivateKey
+	PubKey Prime256v1PublicKey
+}
+
+// NewPrime256v1Identity creates an identity from a raw 32-byte private scalar.
+func NewPrime256v1Identity(pkey []byte) (*Prime256v1Identity, error) {
+	curve := elliptic.P256()
+	d := new(big.Int).SetBytes(pkey)
+	if len(pkey) != prime256v1ScalarLength || d.Sign() == 0 || d.Cmp(curve.Params().N) >= 0 {
+		return nil, errPrime256v1InvalidKey
+	}
+	priv := &ecdsa.PrivateKey{D: d}
+	priv.PublicKey.Curve = curve
+	priv.PublicKey.X, priv.PublicKey.Y = curve.ScalarBaseMult(pkey)
+	return newPrime256v1Identity(priv), nil
+}
+
+// GeneratePrime256v1Identity creates an identity from a freshly generated key.
+func GeneratePrime256v1Identity() (*Prime256v1Identity, error) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return newPrime256v1Identity(priv), nil
+}
+
+func newPrime256v1Identity(priv *ecdsa.PrivateKey) *Prime256v1Identity {
+	return &Prime256v1Identity{
+		PriKey: priv,
+		PubKey: Prime256v1PublicKey{pub: &priv.PublicKey},
+	}
+}
+
+func (t *Prime256v1Identity) SecretKey() []byte {
+	return leftPad(t.PriKey.D.Bytes(), prime256v1ScalarLength)
+}
+
+// Sign hashes the message with SHA-256 and returns the signature in the
+// IEEE P1363 format (r || s) expected by the IC.
+func (t *Prime256v1Identity) Sign(m []byte) ([]byte, error) {
+	hash := sha256.Sum256(m)
+	r, s, err := ecdsa.Sign(rand.Reader, t.PriKey, hash[:])
+	if err != nil {
+		return nil, err
+	}
+	return append(
+		leftPad(r.Bytes(), prime256v1ScalarLength),
+		leftPad(s.Bytes(), prime256v1ScalarLength)...,
+	), nil
+}
+
+func (t *Prime256v1Identity) PublicKey() PublicKey {
+	return &t.PubKey
+}
+
+// ToBytes returns the uncompressed SEC1 encoding of the public key point.
+func (p *Prime256v1PublicKey) ToBytes() []byte {
+	return elliptic.Marshal(p.pub.Curve, p.pub.X, p.pub.Y)
+}
+
+// Verify checks a signature in the IEEE P1363 format over the SHA-256 hash
+// of the message.
+func (p *Prime256v1PublicKey) Verify(message []byte, signature []byte) bool {
+	if len(signature) != 2*prime256v1ScalarLength {
+		return false
+	}
+	hash := sha256.Sum256(message)
+	r := new(big.Int).SetBytes(signature[:prime256v1ScalarLength])
+	s := new(big.Int).SetBytes(signature[prime256v1ScalarLength:])
+	return ecdsa.Verify(p.pub, hash[:], r, s)
+}
+
+// ToDer returns the DER-encoded SubjectPublicKeyInfo with the id-ecPublicKey
+// algorithm and the prime256v1 curve parameter.
+func (p *Prime256v1PublicKey) ToDer() []byte {
+	bytes, err := x509.MarshalPKIXPublicKey(p.pub)
+	if err != nil {
+		panic(err)
+	}
+	return bytes
+}
+
+func leftPad(bs []byte, n int) []byte {
+	if len(bs) >= n {
+		return bs
+	}
+	return append(make([]byte, n-len(bs)), bs...)
+}
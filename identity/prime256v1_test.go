@@ -0,0 +1,47 @@
+package identity_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/icpfans-xyz/agent-go/identity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrime256v1Identity(t *testing.T) {
+	seed, _ := hex.DecodeString("c9afa9d845ba75166b5c215767b1d6934e50c3db36e89b127b8a622b120f6721")
+	id, err := identity.NewPrime256v1Identity(seed)
+	assert.Nil(t, err)
+	assert.Equal(t, seed, id.SecretKey())
+
+	t.Run("der", func(t *testing.T) {
+		der := id.PublicKey().ToDer()
+		assert.Equal(t, "3059301306072a8648ce3d020106082a8648ce3d030107034200", hex.EncodeToString(der[:26]))
+		pub, err := x509.ParsePKIXPublicKey(der)
+		assert.Nil(t, err)
+		assert.True(t, id.PriKey.PublicKey.Equal(pub))
+		assert.Equal(t, der[26:], id.PublicKey().ToBytes())
+	})
+
+	t.Run("sign", func(t *testing.T) {
+		msg := []byte("\x0Aic-request")
+		sig, err := id.Sign(msg)
+		assert.Nil(t, err)
+		assert.Len(t, sig, 64)
+		hash := sha256.Sum256(msg)
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		assert.True(t, ecdsa.Verify(&id.PriKey.PublicKey, hash[:], r, s))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := identity.NewPrime256v1Identity(make([]byte, 32))
+		assert.NotNil(t, err)
+		_, err = identity.NewPrime256v1Identity(seed[:31])
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,32 @@
+// Package remote implements a signer that delegates signing to a service over
+// HTTP, either on a local Unix socket or over the network, so that the
+// secret key never has to be loaded into the agent process.
+//
+// The protocol consists of two JSON endpoints:
+//
+//	GET  /v1/public_key  -> {"public_key": "<hex DER>"}
+//	POST /v1/sign        {"message": "<hex>"} -> {"signature": "<hex>"}
+//
+// Failures are reported with a non-200 status and {"error": "<message>"}.
+package remote
+
+const (
+	PathPublicKey = "/v1/public_key"
+	PathSign      = "/v1/sign"
+)
+
+type PublicKeyResponse struct {
+	PublicKey string `json:"public_key"`
+}
+
+type SignRequest struct {
+	Message string `json:"message"`
+}
+
+type SignResponse struct {
+	Signature string `json:"signature"`
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
@@ -0,0 +1,60 @@
+package remote
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"github.com/icpfans-xyz/agent-go/identity"
+)
+
+// NewHandler serves the remote signer protocol for the signer. It is meant as
+// a reference implementation and a stand-in for real signing services in
+// tests; it does not authenticate callers.
+func NewHandler(signer identity.Signer) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(PathPublicKey, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		writeJSON(w, http.StatusOK, PublicKeyResponse{
+			PublicKey: hex.EncodeToString(signer.PublicKey().ToDer()),
+		})
+	})
+	mux.HandleFunc(PathSign, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		var req SignRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		message, err := hex.DecodeString(req.Message)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		signature, err := signer.Sign(r.Context(), message)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, SignResponse{
+			Signature: hex.EncodeToString(signature),
+		})
+	})
+	return mux
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, ErrorResponse{Error: msg})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
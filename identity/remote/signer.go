@@ -0,0 +1,138 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/icpfans-xyz/agent-go/identity"
+)
+
+const unixScheme = "unix://"
+
+// Signer options that can be used at construction.
+type SignerOptions struct {
+	// The signing service: either "unix:///path/to/socket" or an http(s) URL.
+	Endpoint string
+
+	// If set, sent as a bearer token with every request.
+	Token string
+
+	// The client to use for http(s) endpoints. Defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// Signer implements identity.Signer by calling a remote signing service.
+type Signer struct {
+	client *http.Client
+
+	baseURL string
+
+	token string
+
+	publicKey identity.PublicKey
+}
+
+// NewSigner connects to the signing service and fetches its public key.
+func NewSigner(ctx context.Context, options SignerOptions) (*Signer, error) {
+	s := &Signer{
+		client:  options.Client,
+		baseURL: strings.TrimSuffix(options.Endpoint, "/"),
+		token:   options.Token,
+	}
+	if strings.HasPrefix(options.Endpoint, unixScheme) {
+		socket := strings.TrimPrefix(options.Endpoint, unixScheme)
+		s.client = &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", socket)
+				},
+			},
+		}
+		s.baseURL = "http://unix"
+	}
+	if s.client == nil {
+		s.client = http.DefaultClient
+	}
+
+	var resp PublicKeyResponse
+	if err := s.do(ctx, http.MethodGet, PathPublicKey, nil, &resp); err != nil {
+		return nil, err
+	}
+	der, err := hex.DecodeString(resp.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	s.publicKey, err = identity.PublicKeyFromDer(der)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Signer) PublicKey() identity.PublicKey {
+	return s.publicKey
+}
+
+// Sign asks the signing service to sign the message, and checks the
+// signature against the public key of the service.
+func (s *Signer) Sign(ctx context.Context, message []byte) ([]byte, error) {
+	var resp SignResponse
+	req := SignRequest{Message: hex.EncodeToString(message)}
+	if err := s.do(ctx, http.MethodPost, PathSign, req, &resp); err != nil {
+		return nil, err
+	}
+	sig, err := hex.DecodeString(resp.Signature)
+	if err != nil {
+		return nil, err
+	}
+	// A signature that the IC would reject is better reported here.
+	if !s.publicKey.Verify(message, sig) {
+		return nil, errors.New("remote signer: signature does not match the public key")
+	}
+	return sig, nil
+}
+
+func (s *Signer) do(ctx context.Context, method string, path string, body interface{}, v interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, s.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	if len(s.token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e ErrorResponse
+		if err := json.Unmarshal(data, &e); err == nil && len(e.Error) > 0 {
+			return fmt.Errorf("remote signer: %s", e.Error)
+		}
+		return fmt.Errorf("remote signer: %s", resp.Status)
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,107 @@
+package remote_test
+
+import (
+	"context"
+	"encoding/hex"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/icpfans-xyz/agent-go/agent"
+	"github.com/icpfans-xyz/agent-go/identity"
+	"github.com/icpfans-xyz/agent-go/identity/remote"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupUnixServer(t *testing.T, key identity.KeyPair) string {
+	dir, err := ioutil.TempDir("", "remote")
+	assert.Nil(t, err)
+	socket := filepath.Join(dir, "signer.sock")
+	listener, err := net.Listen("unix", socket)
+	assert.Nil(t, err)
+	server := &http.Server{Handler: remote.NewHandler(identity.NewKeyPairSigner(key))}
+	go server.Serve(listener)
+	t.Cleanup(func() {
+		server.Close()
+		os.RemoveAll(dir)
+	})
+	return "unix://" + socket
+}
+
+// otherKeySigner signs with another key than the one it advertises.
+type otherKeySigner struct {
+	identity.Signer
+	publicKey identity.PublicKey
+}
+
+func (s otherKeySigner) PublicKey() identity.PublicKey {
+	return s.publicKey
+}
+
+func TestRemoteSigner(t *testing.T) {
+	seed, _ := hex.DecodeString("833fe62409237b9d62ec77587520911e9a759cec1d19755b7da901b96dca3d42")
+	key := identity.NewEd25519Identity(seed)
+
+	t.Run("unix", func(t *testing.T) {
+		signer, err := remote.NewSigner(context.Background(), remote.SignerOptions{
+			Endpoint: setupUnixServer(t, key),
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, key.PublicKey().ToDer(), signer.PublicKey().ToDer())
+		assert.Equal(t, key.PublicKey().ToBytes(), signer.PublicKey().ToBytes())
+
+		id := agent.NewSignerIdentity(signer, nil)
+		expected := agent.NewSignIdentity(key, nil)
+		assert.Equal(t, expected.GetPrincipal(), id.GetPrincipal())
+
+		request := agent.Request{Type: "call", CanisterID: []byte{0x01}, MethodName: "greet"}
+		transformed, err := id.TransformRequest(request)
+		assert.Nil(t, err)
+		want, err := expected.TransformRequest(request)
+		assert.Nil(t, err)
+		assert.Equal(t, want.Body, transformed.Body)
+	})
+
+	t.Run("http", func(t *testing.T) {
+		server := httptest.NewServer(remote.NewHandler(identity.NewKeyPairSigner(key)))
+		defer server.Close()
+		signer, err := remote.NewSigner(context.Background(), remote.SignerOptions{
+			Endpoint: server.URL,
+		})
+		assert.Nil(t, err)
+		sig, err := signer.Sign(context.Background(), []byte("hello"))
+		assert.Nil(t, err)
+		assert.Nil(t, identity.Verify(key.PublicKey().ToDer(), []byte("hello"), sig))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err = signer.Sign(ctx, []byte("hello"))
+		assert.NotNil(t, err)
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		other := identity.NewEd25519Identity(make([]byte, 32))
+		server := httptest.NewServer(remote.NewHandler(otherKeySigner{
+			Signer:    identity.NewKeyPairSigner(other),
+			publicKey: key.PublicKey(),
+		}))
+		defer server.Close()
+		signer, err := remote.NewSigner(context.Background(), remote.SignerOptions{
+			Endpoint: server.URL,
+		})
+		assert.Nil(t, err)
+		_, err = signer.Sign(context.Background(), []byte("hello"))
+		assert.EqualError(t, err, "remote signer: signature does not match the public key")
+	})
+
+	t.Run("unavailable", func(t *testing.T) {
+		_, err := remote.NewSigner(context.Background(), remote.SignerOptions{
+			Endpoint: "unix:///nonexistent/signer.sock",
+		})
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,120 @@
+package identity
+
+import (
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+const secp256k1ScalarLength = 32
+
+var errSecp256k1InvalidKey = errors.New("invalid secp256k1 private key")
+
+var (
+	// ecPublicKeyOID is the id-ecPublicKey algorithm identifier from RFC 5480.
+	ecPublicKeyOID = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	// secp256k1OID is the named curve identifier from SEC 2.
+	secp256k1OID = asn1.ObjectIdentifier{1, 3, 132, 0, 10}
+	// prime256v1OID is the named curve identifier from RFC 5480.
+	prime256v1OID = asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}
+)
+
+type Secp256k1PublicKey struct {
+	pub *secp256k1.PublicKey
+}
+
+type Secp256k1Identity struct {
+	PriKey *secp256k1.PrivateKey
+	PubKey Secp256k1PublicKey
+}
+
+// NewSecp256k1Identity creates an identity from a raw 32-byte private scalar.
+func NewSecp256k1Identity(pkey []byte) (*Secp256k1Identity, error) {
+	var d secp256k1.ModNScalar
+	if len(pkey) != secp256k1ScalarLength || d.SetByteSlice(pkey) || d.IsZero() {
+		return nil, errSecp256k1InvalidKey
+	}
+	return newSecp256k1Identity(secp256k1.NewPrivateKey(&d)), nil
+}
+
+// GenerateSecp256k1Identity creates an identity from a freshly generated key.
+func GenerateSecp256k1Identity() (*Secp256k1Identity, error) {
+	priv, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	return newSecp256k1Identity(priv), nil
+}
+
+func newSecp256k1Identity(priv *secp256k1.PrivateKey) *Secp256k1Identity {
+	return &Secp256k1Identity{
+		PriKey: priv,
+		PubKey: Secp256k1PublicKey{pub: priv.PubKey()},
+	}
+}
+
+func (t *Secp256k1Identity) SecretKey() []byte {
+	return t.PriKey.Serialize()
+}
+
+// Sign hashes the message with SHA-256 and returns the low-S signature in the
+// IEEE P1363 format (r || s) expected by the IC.
+func (t *Secp256k1Identity) Sign(m []byte) ([]byte, error) {
+	hash := sha256.Sum256(m)
+	// The compact format is a recovery byte followed by r || s.
+	return ecdsa.SignCompact(t.PriKey, hash[:], false)[1:], nil
+}
+
+func (t *Secp256k1Identity) PublicKey() PublicKey {
+	return &t.PubKey
+}
+
+// ToBytes returns the uncompressed SEC1 encoding of the public key point.
+func (p *Secp256k1PublicKey) ToBytes() []byte {
+	return p.pub.SerializeUncompressed()
+}
+
+// Verify checks a signature in the IEEE P1363 format over the SHA-256 hash
+// of the message.
+func (p *Secp256k1PublicKey) Verify(message []byte, signature []byte) bool {
+	if len(signature) != 2*secp256k1ScalarLength {
+		return false
+	}
+	var r, s secp256k1.ModNScalar
+	if r.SetByteSlice(signature[:secp256k1ScalarLength]) || s.SetByteSlice(signature[secp256k1ScalarLength:]) {
+		return false
+	}
+	hash := sha256.Sum256(message)
+	return ecdsa.NewSignature(&r, &s).Verify(hash[:], p.pub)
+}
+
+// ToDer returns the DER-encoded SubjectPublicKeyInfo with the id-ecPublicKey
+// algorithm and the secp256k1 curve parameter.
+func (p *Secp256k1PublicKey) ToDer() []byte {
+	bytes, err := marshalECPublicKey(secp256k1OID, p.ToBytes())
+	if err != nil {
+		panic(err)
+	}
+	return bytes
+}
+
+func marshalECPublicKey(curve asn1.ObjectIdentifier, point []byte) ([]byte, error) {
+	params, err := asn1.Marshal(curve)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(subjectPublicKeyInfo{
+		Algorithm: pkix.AlgorithmIdentifier{
+			Algorithm:  ecPublicKeyOID,
+			Parameters: asn1.RawValue{FullBytes: params},
+		},
+		PublicKey: asn1.BitString{
+			BitLength: len(point) * 8,
+			Bytes:     point,
+		},
+	})
+}
@@ -0,0 +1,30 @@
+package identity
+
+import "context"
+
+// Signer is a key that can sign messages without exposing its secret key,
+// e.g. a key held by an HSM, a cloud KMS or a signing daemon.
+type Signer interface {
+	PublicKey() PublicKey
+	Sign(ctx context.Context, message []byte) ([]byte, error)
+}
+
+type keyPairSigner struct {
+	key KeyPair
+}
+
+// NewKeyPairSigner returns a Signer that signs with the key pair.
+func NewKeyPairSigner(key KeyPair) Signer {
+	return &keyPairSigner{key: key}
+}
+
+func (s *keyPairSigner) PublicKey() PublicKey {
+	return s.key.PublicKey()
+}
+
+func (s *keyPairSigner) Sign(ctx context.Context, message []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.key.Sign(message)
+}
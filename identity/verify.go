@@ -0,0 +1,78 @@
+package identity
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"encoding/asn1"
+	"errors"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+var (
+	errInvalidSignature     = errors.New("invalid signature")
+	errUnsupportedPublicKey = errors.New("unsupported public key algorithm")
+	errCanisterSigVerify    = errors.New("canister signatures require a certificate check, see agent.VerifyCanisterSignature")
+)
+
+// PublicKeyFromDer parses a DER-encoded SubjectPublicKeyInfo, detecting the
+// algorithm from its object identifier. Ed25519, secp256k1, prime256v1 and
+// canister signature keys are supported.
+func PublicKeyFromDer(der []byte) (PublicKey, error) {
+	var spki subjectPublicKeyInfo
+	if rest, err := asn1.Unmarshal(der, &spki); err != nil {
+		return nil, err
+	} else if len(rest) != 0 {
+		return nil, errors.New("trailing data after public key")
+	}
+	point := spki.PublicKey.RightAlign()
+	switch {
+	case spki.Algorithm.Algorithm.Equal(ed25519OID):
+		if len(point) != ed25519.PublicKeySize {
+			return nil, errEd25519WrongKeyType
+		}
+		return &Ed25519PublicKey{pub: ed25519.PublicKey(point)}, nil
+	case spki.Algorithm.Algorithm.Equal(ecPublicKeyOID):
+		var curve asn1.ObjectIdentifier
+		if _, err := asn1.Unmarshal(spki.Algorithm.Parameters.FullBytes, &curve); err != nil {
+			return nil, err
+		}
+		switch {
+		case curve.Equal(secp256k1OID):
+			pub, err := secp256k1.ParsePubKey(point)
+			if err != nil {
+				return nil, err
+			}
+			return &Secp256k1PublicKey{pub: pub}, nil
+		case curve.Equal(prime256v1OID):
+			x, y := elliptic.Unmarshal(elliptic.P256(), point)
+			if x == nil {
+				return nil, errors.New("invalid prime256v1 public key")
+			}
+			return &Prime256v1PublicKey{pub: &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}}, nil
+		default:
+			return nil, errPemUnknownCurve
+		}
+	case spki.Algorithm.Algorithm.Equal(canisterSigOID):
+		return parseCanisterSigPublicKey(point)
+	default:
+		return nil, errUnsupportedPublicKey
+	}
+}
+
+// Verify checks that the signature of the message was made by the key with
+// the given DER-encoded public key.
+func Verify(der []byte, message []byte, signature []byte) error {
+	pub, err := PublicKeyFromDer(der)
+	if err != nil {
+		return err
+	}
+	if _, ok := pub.(*CanisterSigPublicKey); ok {
+		return errCanisterSigVerify
+	}
+	if !pub.Verify(message, signature) {
+		return errInvalidSignature
+	}
+	return nil
+}
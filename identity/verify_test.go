@@ -0,0 +1,60 @@
+package identity_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/icpfans-xyz/agent-go/identity"
+	"github.com/icpfans-xyz/agent-go/principal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublicKeyFromDer(t *testing.T) {
+	seed, _ := hex.DecodeString("833fe62409237b9d62ec77587520911e9a759cec1d19755b7da901b96dca3d42")
+	secp256k1, err := identity.GenerateSecp256k1Identity()
+	assert.Nil(t, err)
+	prime256v1, err := identity.GeneratePrime256v1Identity()
+	assert.Nil(t, err)
+	keys := map[string]identity.KeyPair{
+		"ed25519":    identity.NewEd25519Identity(seed),
+		"secp256k1":  secp256k1,
+		"prime256v1": prime256v1,
+	}
+	msg := []byte("\x0Aic-request")
+	for name, key := range keys {
+		t.Run(name, func(t *testing.T) {
+			pub, err := identity.PublicKeyFromDer(key.PublicKey().ToDer())
+			assert.Nil(t, err)
+			assert.Equal(t, key.PublicKey().ToBytes(), pub.ToBytes())
+			assert.Equal(t, key.PublicKey().ToDer(), pub.ToDer())
+
+			sig, err := key.Sign(msg)
+			assert.Nil(t, err)
+			assert.True(t, pub.Verify(msg, sig))
+			assert.True(t, key.PublicKey().Verify(msg, sig))
+			assert.False(t, pub.Verify([]byte("other"), sig))
+			assert.False(t, pub.Verify(msg, sig[1:]))
+			assert.Nil(t, identity.Verify(pub.ToDer(), msg, sig))
+			sig[0] ^= 0xff
+			assert.False(t, pub.Verify(msg, sig))
+			assert.NotNil(t, identity.Verify(pub.ToDer(), msg, sig))
+		})
+	}
+
+	t.Run("canister", func(t *testing.T) {
+		canisterId, _ := principal.FromString("rwlgt-iiaaa-aaaaa-aaaaa-cai")
+		key := identity.NewCanisterSigPublicKey(canisterId, []byte("seed"))
+		pub, err := identity.PublicKeyFromDer(key.ToDer())
+		assert.Nil(t, err)
+		assert.Equal(t, key, pub)
+		assert.False(t, pub.Verify(msg, []byte("sig")))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := identity.PublicKeyFromDer([]byte{0x30, 0x00})
+		assert.NotNil(t, err)
+		der := keys["ed25519"].PublicKey().ToDer()
+		_, err = identity.PublicKeyFromDer(append(der, 0x00))
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,112 @@
+// Package ledger implements account identifiers of the ICP ledger: see
+// https://smartcontracts.org/docs/integration/ledger-quick-start.html#_accounts.
+package ledger
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"hash/crc32"
+
+	"github.com/icpfans-xyz/agent-go/principal"
+)
+
+// Length of the CRC32 checksum prepended to the hash of an account identifier.
+const checksumLength = 4
+
+var accountDomainSeparator = []byte("\x0Aaccount-id")
+
+var (
+	ErrInvalidLength   = errors.New("account identifier must be 32 bytes long")
+	ErrInvalidChecksum = errors.New("account identifier does not have a valid checksum")
+)
+
+// Subaccount distinguishes the accounts owned by the same principal.
+type Subaccount [32]byte
+
+// DefaultSubaccount is the subaccount of the main account of a principal.
+var DefaultSubaccount = Subaccount{}
+
+// SubaccountFromPrincipal returns the subaccount derived from a principal,
+// which is its length followed by its bytes, padded with zeros.
+func SubaccountFromPrincipal(p *principal.Principal) Subaccount {
+	var sub Subaccount
+	sub[0] = byte(len(p.Bytes))
+	copy(sub[1:], p.Bytes)
+	return sub
+}
+
+// SubaccountFromUint64 returns the subaccount with the big-endian integer in
+// its last 8 bytes.
+func SubaccountFromUint64(n uint64) Subaccount {
+	var sub Subaccount
+	binary.BigEndian.PutUint64(sub[len(sub)-8:], n)
+	return sub
+}
+
+// AccountIdentifier is the CRC32 checksum followed by the SHA-224 hash of
+// the principal and subaccount owning an account.
+type AccountIdentifier [32]byte
+
+// NewAccountIdentifier returns the account identifier of the principal and
+// subaccount. A nil subaccount is the default subaccount.
+func NewAccountIdentifier(p *principal.Principal, sub *Subaccount) AccountIdentifier {
+	if sub == nil {
+		sub = &DefaultSubaccount
+	}
+	hasher := sha256.New224()
+	hasher.Write(accountDomainSeparator)
+	hasher.Write(p.Bytes)
+	hasher.Write(sub[:])
+	hash := hasher.Sum(nil)
+
+	var id AccountIdentifier
+	binary.BigEndian.PutUint32(id[:checksumLength], crc32.ChecksumIEEE(hash))
+	copy(id[checksumLength:], hash)
+	return id
+}
+
+// AccountIdentifierFromBytes checks the length and checksum of an account
+// identifier.
+func AccountIdentifierFromBytes(data []byte) (AccountIdentifier, error) {
+	var id AccountIdentifier
+	if len(data) != len(id) {
+		return id, ErrInvalidLength
+	}
+	copy(id[:], data)
+	if !id.IsValid() {
+		return AccountIdentifier{}, ErrInvalidChecksum
+	}
+	return id, nil
+}
+
+// AccountIdentifierFromHex parses the hex encoding of an account identifier,
+// checking its length and checksum.
+func AccountIdentifierFromHex(s string) (AccountIdentifier, error) {
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return AccountIdentifier{}, err
+	}
+	return AccountIdentifierFromBytes(data)
+}
+
+// IsValid reports whether the checksum matches the hash.
+func (id AccountIdentifier) IsValid() bool {
+	checksum := make([]byte, checksumLength)
+	binary.BigEndian.PutUint32(checksum, crc32.ChecksumIEEE(id[checksumLength:]))
+	return bytes.Equal(checksum, id[:checksumLength])
+}
+
+func (id AccountIdentifier) Bytes() []byte {
+	return id[:]
+}
+
+func (id AccountIdentifier) ToHex() string {
+	return hex.EncodeToString(id[:])
+}
+
+func (id AccountIdentifier) String() string {
+	return id.ToHex()
+}
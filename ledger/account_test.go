@@ -0,0 +1,50 @@
+package ledger_test
+
+import (
+	"testing"
+
+	"github.com/icpfans-xyz/agent-go/ledger"
+	"github.com/icpfans-xyz/agent-go/principal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccountIdentifier(t *testing.T) {
+	anonymous := "1c7a48ba6a562aa9eaa2481a9049cdf0433b9738c992d698c31d8abf89cadc79"
+
+	t.Run("new", func(t *testing.T) {
+		id := ledger.NewAccountIdentifier(principal.Anonymous(), nil)
+		assert.Equal(t, anonymous, id.ToHex())
+		assert.Equal(t, id, ledger.NewAccountIdentifier(principal.Anonymous(), &ledger.DefaultSubaccount))
+		assert.True(t, id.IsValid())
+
+		sub := ledger.SubaccountFromUint64(1)
+		other := ledger.NewAccountIdentifier(principal.Anonymous(), &sub)
+		assert.NotEqual(t, id, other)
+		assert.True(t, other.IsValid())
+	})
+
+	t.Run("hex", func(t *testing.T) {
+		id, err := ledger.AccountIdentifierFromHex(anonymous)
+		assert.Nil(t, err)
+		assert.Equal(t, anonymous, id.String())
+
+		_, err = ledger.AccountIdentifierFromHex(anonymous[:62])
+		assert.Equal(t, ledger.ErrInvalidLength, err)
+		_, err = ledger.AccountIdentifierFromHex("00" + anonymous[2:])
+		assert.Equal(t, ledger.ErrInvalidChecksum, err)
+		_, err = ledger.AccountIdentifierFromHex("zz" + anonymous[2:])
+		assert.NotNil(t, err)
+	})
+
+	t.Run("subaccount", func(t *testing.T) {
+		sub := ledger.SubaccountFromUint64(0x0102)
+		assert.Equal(t, byte(0x01), sub[30])
+		assert.Equal(t, byte(0x02), sub[31])
+
+		p, _ := principal.FromString("rrkah-fqaaa-aaaaa-aaaaq-cai")
+		sub = ledger.SubaccountFromPrincipal(p)
+		assert.Equal(t, byte(10), sub[0])
+		assert.Equal(t, p.Bytes, sub[1:11])
+		assert.Equal(t, make([]byte, 21), sub[11:])
+	})
+}
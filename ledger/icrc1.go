@@ -0,0 +1,181 @@
+package ledger
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/icpfans-xyz/agent-go/candid/idl"
+	"github.com/icpfans-xyz/agent-go/principal"
+	"github.com/icpfans-xyz/agent-go/principal/utils"
+	icprincipal "github.com/mix-labs/IC-Go/utils/principal"
+)
+
+var (
+	ErrInvalidAccountChecksum = errors.New("account does not have a valid checksum")
+	ErrNonCanonicalAccount    = errors.New("account text is not in its canonical form")
+)
+
+// Account is an account of an ICRC-1 ledger: see
+// https://github.com/dfinity/ICRC-1/blob/main/standards/ICRC-1/README.md.
+type Account struct {
+	Owner *principal.Principal
+	// The subaccount of the account, nil for the default subaccount.
+	Subaccount *Subaccount
+}
+
+// AccountType is the Candid type of accounts,
+// record { owner : principal; subaccount : opt blob }.
+func AccountType() *idl.Rec {
+	return idl.NewRec(map[string]idl.Type{
+		"owner":      new(idl.Principal),
+		"subaccount": idl.NewOpt(idl.NewVec(idl.Nat8())),
+	})
+}
+
+// isDefault reports whether the account uses the default subaccount.
+func (a Account) isDefault() bool {
+	return a.Subaccount == nil || *a.Subaccount == DefaultSubaccount
+}
+
+func (a Account) checksum() string {
+	data := append([]byte{}, a.Owner.Bytes...)
+	data = append(data, a.Subaccount[:]...)
+	return utils.Base32Encode(utils.FromUint32(utils.Crc32(data)))
+}
+
+// String returns the textual form of the account: the owner for the default
+// subaccount, otherwise <owner>-<checksum>.<subaccount> with the subaccount
+// in hex without leading zeros.
+func (a Account) String() string {
+	if a.isDefault() {
+		return a.Owner.ToString()
+	}
+	sub := strings.TrimLeft(hex.EncodeToString(a.Subaccount[:]), "0")
+	return fmt.Sprintf("%s-%s.%s", a.Owner.ToString(), a.checksum(), sub)
+}
+
+// AccountFromString parses the textual form of an account, checking its
+// checksum and that it is canonical.
+func AccountFromString(text string) (*Account, error) {
+	i := strings.LastIndex(text, ".")
+	if i < 0 {
+		owner, err := principal.FromString(text)
+		if err != nil {
+			return nil, err
+		}
+		return &Account{Owner: owner}, nil
+	}
+	prefix, subHex := text[:i], text[i+1:]
+	j := strings.LastIndex(prefix, "-")
+	if j < 0 {
+		return nil, ErrNonCanonicalAccount
+	}
+	owner, err := principal.FromString(prefix[:j])
+	if err != nil {
+		return nil, err
+	}
+	if len(subHex) == 0 || len(subHex) > 2*len(Subaccount{}) || subHex[0] == '0' {
+		return nil, ErrNonCanonicalAccount
+	}
+	if len(subHex)%2 != 0 {
+		subHex = "0" + subHex
+	}
+	data, err := hex.DecodeString(subHex)
+	if err != nil {
+		return nil, err
+	}
+	var sub Subaccount
+	copy(sub[len(sub)-len(data):], data)
+	account := &Account{Owner: owner, Subaccount: &sub}
+	if account.checksum() != prefix[j+1:] {
+		return nil, ErrInvalidAccountChecksum
+	}
+	return account, nil
+}
+
+// ToCandid returns the value of the account for AccountType.
+func (a Account) ToCandid() map[string]interface{} {
+	var sub interface{}
+	if a.Subaccount != nil {
+		bytes := make([]interface{}, len(a.Subaccount))
+		for i, b := range a.Subaccount {
+			bytes[i] = big.NewInt(int64(b))
+		}
+		sub = bytes
+	}
+	return map[string]interface{}{
+		"owner":      icprincipal.Principal(a.Owner.Bytes),
+		"subaccount": sub,
+	}
+}
+
+// AccountFromCandid converts a decoded value of AccountType to an account.
+func AccountFromCandid(v interface{}) (*Account, error) {
+	fields, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid account: %v", v)
+	}
+	owner, ok := candidField(fields, "owner").(icprincipal.Principal)
+	if !ok {
+		return nil, fmt.Errorf("invalid account owner: %v", candidField(fields, "owner"))
+	}
+	account := &Account{Owner: principal.NewPrincipal([]byte(owner))}
+	subaccount := candidField(fields, "subaccount")
+	if subaccount == nil {
+		return account, nil
+	}
+	bytes, ok := subaccount.([]interface{})
+	if !ok || len(bytes) != len(Subaccount{}) {
+		return nil, fmt.Errorf("invalid account subaccount: %v", subaccount)
+	}
+	var sub Subaccount
+	for i, b := range bytes {
+		n, ok := b.(*big.Int)
+		if !ok || !n.IsUint64() || n.Uint64() > 0xff {
+			return nil, fmt.Errorf("invalid account subaccount: %v", subaccount)
+		}
+		sub[i] = byte(n.Uint64())
+	}
+	account.Subaccount = &sub
+	return account, nil
+}
+
+// candidField returns the field of a decoded record, which is keyed by the
+// hash of its name when the name is unknown to the decoder.
+func candidField(fields map[string]interface{}, name string) interface{} {
+	if v, ok := fields[name]; ok {
+		return v
+	}
+	return fields[idl.Hash(name).String()]
+}
+
+// MarshalText encodes the account in its textual form.
+func (a Account) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+func (a *Account) UnmarshalText(text []byte) error {
+	account, err := AccountFromString(string(text))
+	if err != nil {
+		return err
+	}
+	*a = *account
+	return nil
+}
+
+// MarshalJSON encodes the account as a JSON string in its textual form.
+func (a Account) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+func (a *Account) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	return a.UnmarshalText([]byte(text))
+}
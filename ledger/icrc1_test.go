@@ -0,0 +1,74 @@
+package ledger_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/icpfans-xyz/agent-go/candid/idl"
+	"github.com/icpfans-xyz/agent-go/ledger"
+	"github.com/icpfans-xyz/agent-go/principal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccount(t *testing.T) {
+	owner, err := principal.FromString("k2t6j-2nvnp-4zjm3-25dtz-6xhaa-c7boj-5gayf-oj3xs-i43lp-teztq-6ae")
+	assert.Nil(t, err)
+	var sequence ledger.Subaccount
+	for i := range sequence {
+		sequence[i] = byte(i + 1)
+	}
+	one := ledger.SubaccountFromUint64(1)
+	accounts := map[string]ledger.Account{
+		"k2t6j-2nvnp-4zjm3-25dtz-6xhaa-c7boj-5gayf-oj3xs-i43lp-teztq-6ae":                                                                         {Owner: owner},
+		"k2t6j-2nvnp-4zjm3-25dtz-6xhaa-c7boj-5gayf-oj3xs-i43lp-teztq-6ae-6cc627i.1":                                                               {Owner: owner, Subaccount: &one},
+		"k2t6j-2nvnp-4zjm3-25dtz-6xhaa-c7boj-5gayf-oj3xs-i43lp-teztq-6ae-dfxgiyy.102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20": {Owner: owner, Subaccount: &sequence},
+	}
+
+	t.Run("text", func(t *testing.T) {
+		for text, account := range accounts {
+			assert.Equal(t, text, account.String())
+			parsed, err := ledger.AccountFromString(text)
+			assert.Nil(t, err)
+			assert.Equal(t, account.String(), parsed.String())
+		}
+		assert.Equal(t, owner.ToString(), ledger.Account{Owner: owner, Subaccount: &ledger.DefaultSubaccount}.String())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for text, expected := range map[string]error{
+			"k2t6j-2nvnp-4zjm3-25dtz-6xhaa-c7boj-5gayf-oj3xs-i43lp-teztq-6ae-6cc627j.1":  ledger.ErrInvalidAccountChecksum,
+			"k2t6j-2nvnp-4zjm3-25dtz-6xhaa-c7boj-5gayf-oj3xs-i43lp-teztq-6ae-6cc627i.01": ledger.ErrNonCanonicalAccount,
+			"k2t6j-2nvnp-4zjm3-25dtz-6xhaa-c7boj-5gayf-oj3xs-i43lp-teztq-6ae-6cc627i.":   ledger.ErrNonCanonicalAccount,
+			"k2t6j.1": ledger.ErrNonCanonicalAccount,
+		} {
+			_, err := ledger.AccountFromString(text)
+			assert.Equal(t, expected, err, text)
+		}
+		_, err := ledger.AccountFromString("k2t6j-2nvnp-4zjm3-25dtz-6xhaa-c7boj-5gayf-oj3xs-i43lp-teztq-6ae-6cc627i.1g")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		data, err := json.Marshal(accounts["k2t6j-2nvnp-4zjm3-25dtz-6xhaa-c7boj-5gayf-oj3xs-i43lp-teztq-6ae-6cc627i.1"])
+		assert.Nil(t, err)
+		assert.Equal(t, `"k2t6j-2nvnp-4zjm3-25dtz-6xhaa-c7boj-5gayf-oj3xs-i43lp-teztq-6ae-6cc627i.1"`, string(data))
+		var decoded ledger.Account
+		assert.Nil(t, json.Unmarshal(data, &decoded))
+		assert.Equal(t, one, *decoded.Subaccount)
+		assert.NotNil(t, json.Unmarshal([]byte(`"invalid"`), &decoded))
+	})
+
+	t.Run("candid", func(t *testing.T) {
+		for _, account := range accounts {
+			data, err := idl.Encode([]idl.Type{ledger.AccountType()}, []interface{}{account.ToCandid()})
+			assert.Nil(t, err)
+			_, values, err := idl.Decode(data)
+			assert.Nil(t, err)
+			decoded, err := ledger.AccountFromCandid(values[0])
+			assert.Nil(t, err)
+			assert.Equal(t, account.String(), decoded.String())
+		}
+		_, err := ledger.AccountFromCandid("invalid")
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,76 @@
+package principal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// Length of the index of a canister id, which is followed by two bytes.
+const canisterIndexLength = 8
+
+var ErrNotCanisterId = errors.New("principal is not a canister id")
+
+// FromCanisterIndex returns the canister id with the given index, which is
+// the big-endian index followed by 0x01 0x01.
+func FromCanisterIndex(index uint64) *Principal {
+	bytes := make([]byte, canisterIndexLength, canisterIndexLength+2)
+	binary.BigEndian.PutUint64(bytes, index)
+	return NewPrincipal(append(bytes, 0x01, OPAQUE_SUFFIX))
+}
+
+// CanisterIndex returns the index of a canister id built by
+// FromCanisterIndex.
+func (p *Principal) CanisterIndex() (uint64, error) {
+	if len(p.Bytes) != canisterIndexLength+2 || p.Bytes[canisterIndexLength] != 0x01 || p.Bytes[canisterIndexLength+1] != OPAQUE_SUFFIX {
+		return 0, ErrNotCanisterId
+	}
+	return binary.BigEndian.Uint64(p.Bytes[:canisterIndexLength]), nil
+}
+
+// CanisterRange is an inclusive range of canister ids, as assigned to
+// subnets.
+type CanisterRange struct {
+	_     struct{} `cbor:",toarray"`
+	Start *Principal
+	End   *Principal
+}
+
+func NewCanisterRange(start *Principal, end *Principal) CanisterRange {
+	return CanisterRange{Start: start, End: end}
+}
+
+// Contains reports whether the principal is within the range, comparing
+// principals by their bytes.
+func (r CanisterRange) Contains(p *Principal) bool {
+	return bytes.Compare(r.Start.Bytes, p.Bytes) <= 0 && bytes.Compare(p.Bytes, r.End.Bytes) <= 0
+}
+
+type CanisterRanges []CanisterRange
+
+// Contains reports whether one of the ranges contains the principal.
+func (rs CanisterRanges) Contains(p *Principal) bool {
+	for _, r := range rs {
+		if r.Contains(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseCanisterRanges decodes the CBOR-encoded canister ranges of a subnet,
+// found at /subnet/<subnet_id>/canister_ranges in the state tree.
+func ParseCanisterRanges(data []byte) (CanisterRanges, error) {
+	var ranges CanisterRanges
+	if err := cbor.Unmarshal(data, &ranges); err != nil {
+		return nil, err
+	}
+	for _, r := range ranges {
+		if r.Start == nil || r.End == nil || r.Start.Compare(r.End) > 0 {
+			return nil, errors.New("invalid canister range")
+		}
+	}
+	return ranges, nil
+}
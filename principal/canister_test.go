@@ -0,0 +1,52 @@
+package principal_test
+
+import (
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/icpfans-xyz/agent-go/principal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanisterIndex(t *testing.T) {
+	for text, index := range map[string]uint64{
+		"rrkah-fqaaa-aaaaa-aaaaq-cai": 1,
+		"ryjl3-tyaaa-aaaaa-aaaba-cai": 2,
+		"rdmx6-jaaaa-aaaaa-aaadq-cai": 7,
+	} {
+		p, err := principal.FromString(text)
+		assert.Nil(t, err)
+		assert.Equal(t, text, principal.FromCanisterIndex(index).ToString())
+		got, err := p.CanisterIndex()
+		assert.Nil(t, err)
+		assert.Equal(t, index, got)
+	}
+	_, err := principal.Anonymous().CanisterIndex()
+	assert.Equal(t, principal.ErrNotCanisterId, err)
+}
+
+func TestCanisterRanges(t *testing.T) {
+	ranges := principal.CanisterRanges{
+		principal.NewCanisterRange(principal.FromCanisterIndex(0), principal.FromCanisterIndex(99)),
+		principal.NewCanisterRange(principal.FromCanisterIndex(200), principal.FromCanisterIndex(299)),
+	}
+	assert.True(t, ranges.Contains(principal.FromCanisterIndex(0)))
+	assert.True(t, ranges.Contains(principal.FromCanisterIndex(99)))
+	assert.True(t, ranges.Contains(principal.FromCanisterIndex(250)))
+	assert.False(t, ranges.Contains(principal.FromCanisterIndex(100)))
+	assert.False(t, ranges.Contains(principal.FromCanisterIndex(300)))
+	assert.False(t, ranges.Contains(principal.Anonymous()))
+
+	t.Run("parse", func(t *testing.T) {
+		data, err := cbor.Marshal(ranges)
+		assert.Nil(t, err)
+		parsed, err := principal.ParseCanisterRanges(append([]byte{0xd9, 0xd9, 0xf7}, data...))
+		assert.Nil(t, err)
+		assert.Equal(t, ranges, parsed)
+
+		data, err = cbor.Marshal([][][]byte{{principal.FromCanisterIndex(2).Bytes, principal.FromCanisterIndex(1).Bytes}})
+		assert.Nil(t, err)
+		_, err = principal.ParseCanisterRanges(data)
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,86 @@
+package principal
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+func (p Principal) String() string {
+	return p.ToString()
+}
+
+// Equal reports whether both principals have the same bytes.
+func (p *Principal) Equal(other *Principal) bool {
+	return bytes.Equal(p.Bytes, other.Bytes)
+}
+
+// Compare orders principals by their bytes, as the IC does. The result is 0
+// if p == other, -1 if p < other, and +1 if p > other.
+func (p *Principal) Compare(other *Principal) int {
+	return bytes.Compare(p.Bytes, other.Bytes)
+}
+
+// MarshalCBOR encodes the principal as a CBOR byte string.
+func (p Principal) MarshalCBOR() ([]byte, error) {
+	return cbor.Marshal(p.Bytes)
+}
+
+func (p *Principal) UnmarshalCBOR(data []byte) error {
+	var bytes []byte
+	if err := cbor.Unmarshal(data, &bytes); err != nil {
+		return err
+	}
+	if len(bytes) > MAX_LENGTH_IN_BYTES {
+		return ErrTooLong
+	}
+	p.Bytes = bytes
+	return nil
+}
+
+// MarshalText encodes the principal in its textual form.
+func (p Principal) MarshalText() ([]byte, error) {
+	return []byte(p.ToString()), nil
+}
+
+func (p *Principal) UnmarshalText(text []byte) error {
+	principal, err := FromString(string(text))
+	if err != nil {
+		return err
+	}
+	p.Bytes = principal.Bytes
+	return nil
+}
+
+// MarshalJSON encodes the principal as a JSON string in its textual form.
+func (p Principal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.ToString())
+}
+
+func (p *Principal) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	return p.UnmarshalText([]byte(text))
+}
+
+// Value stores the principal in a database column in its textual form.
+func (p Principal) Value() (driver.Value, error) {
+	return p.ToString(), nil
+}
+
+// Scan reads a principal stored in its textual form.
+func (p *Principal) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case string:
+		return p.UnmarshalText([]byte(src))
+	case []byte:
+		return p.UnmarshalText(src)
+	default:
+		return fmt.Errorf("cannot scan %T into a principal", src)
+	}
+}
@@ -0,0 +1,29 @@
+package principal
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrTooShort         = errors.New("principal text is too short")
+	ErrTooLong          = errors.New("principal is longer than 29 bytes")
+	ErrInvalidCharacter = errors.New("principal text contains an invalid character")
+	ErrInvalidChecksum  = errors.New("principal does not have a valid checksum")
+	ErrInvalidFormat    = errors.New("principal text is not grouped in 5 characters separated by dashes")
+)
+
+// ParseError is returned when the textual form of a principal is invalid.
+// Err is one of the errors above.
+type ParseError struct {
+	Text string
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("invalid principal %q: %v", e.Text, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
@@ -1,15 +1,64 @@
 package principal
 
 import (
-	"errors"
+	"bytes"
 	"regexp"
 	"strings"
 
 	"github.com/icpfans-xyz/agent-go/principal/utils"
 )
 
+const OPAQUE_SUFFIX = 0x01
 const SELF_AUTHENTICATING_SUFFIX = 0x02
+const DERIVED_SUFFIX = 0x03
 const ANONYMOUS_SUFFIX = 0x04
+const RESERVED_SUFFIX = 0x7f
+
+// The maximum length of a principal in bytes.
+const MAX_LENGTH_IN_BYTES = 29
+
+// Length of the CRC32 checksum prepended to the bytes of the textual form.
+const checksumLength = 4
+
+// Class is the class of a principal, given by its last byte: see
+// https://smartcontracts.org/docs/interface-spec/index.html#principal.
+type Class int
+
+const (
+	// ClassUnassigned is a principal with a suffix that has no class yet.
+	ClassUnassigned Class = iota
+	// ClassManagement is the empty principal of the management canister.
+	ClassManagement
+	// ClassOpaque is a principal chosen by the IC, such as a canister id.
+	ClassOpaque
+	// ClassSelfAuthenticating is the hash of a public key.
+	ClassSelfAuthenticating
+	// ClassDerived is derived from a self-authenticating principal.
+	ClassDerived
+	// ClassAnonymous is the principal of unauthenticated callers.
+	ClassAnonymous
+	// ClassReserved is not used by the IC.
+	ClassReserved
+)
+
+func (c Class) String() string {
+	switch c {
+	case ClassManagement:
+		return "management"
+	case ClassOpaque:
+		return "opaque"
+	case ClassSelfAuthenticating:
+		return "self-authenticating"
+	case ClassDerived:
+		return "derived"
+	case ClassAnonymous:
+		return "anonymous"
+	case ClassReserved:
+		return "reserved"
+	default:
+		return "unassigned"
+	}
+}
 
 type Principal struct {
 	Bytes []byte
@@ -19,8 +68,14 @@ func NewPrincipal(bytes []byte) *Principal {
 	return &Principal{Bytes: bytes}
 }
 
+// Anonymous returns the principal of unauthenticated callers, "2vxsx-fae".
 func Anonymous() *Principal {
-	return NewPrincipal(make([]byte, ANONYMOUS_SUFFIX))
+	return NewPrincipal([]byte{ANONYMOUS_SUFFIX})
+}
+
+// Management returns the principal of the management canister, "aaaaa-aa".
+func Management() *Principal {
+	return NewPrincipal([]byte{})
 }
 
 func SelfAuthenticating(publicKey []byte) (*Principal, error) {
@@ -34,36 +89,75 @@ func FromHex(hex string) (*Principal, error) {
 	if err != nil {
 		return nil, err
 	}
-	return NewPrincipal(bytes), nil
+	return FromBytes(bytes)
 }
 
+// FromString parses the textual form of a principal, checking its checksum
+// and that it is grouped in 5 characters separated by dashes. Upper case
+// characters are accepted.
 func FromString(str string) (*Principal, error) {
 	lowerStr := strings.ToLower(str)
 	fixedStr := strings.ReplaceAll(lowerStr, "-", "")
-	bytes, err := utils.Base32Decode(fixedStr)
+	data, err := utils.Base32Decode(fixedStr)
 	if err != nil {
-		return nil, err
+		return nil, &ParseError{Text: str, Err: ErrInvalidCharacter}
+	}
+	if len(data) < checksumLength {
+		return nil, &ParseError{Text: str, Err: ErrTooShort}
+	}
+	if len(data)-checksumLength > MAX_LENGTH_IN_BYTES {
+		return nil, &ParseError{Text: str, Err: ErrTooLong}
+	}
+	p := NewPrincipal(data[checksumLength:])
+	if !bytes.Equal(data[:checksumLength], utils.FromUint32(utils.Crc32(p.Bytes))) {
+		return nil, &ParseError{Text: str, Err: ErrInvalidChecksum}
 	}
-	bytes = bytes[4:]
-	p := NewPrincipal(bytes)
-	if p.ToString() != str {
-		return nil, errors.New("Principal does not have a valid checksum")
+	if p.ToString() != lowerStr {
+		return nil, &ParseError{Text: str, Err: ErrInvalidFormat}
 	}
 	return p, nil
 }
 
+// FromBytes creates a principal from its binary form, which must not be
+// longer than 29 bytes.
 func FromBytes(bytes []byte) (*Principal, error) {
+	if len(bytes) > MAX_LENGTH_IN_BYTES {
+		return nil, ErrTooLong
+	}
 	return NewPrincipal(bytes), nil
 }
 
-func (p *Principal) MarshalCBOR() ([]byte, error) {
-	return p.ToBytes(), nil
-}
-
 func (p *Principal) IsAnonymous() bool {
 	return len(p.Bytes) == 1 && p.Bytes[0] == ANONYMOUS_SUFFIX
 }
 
+// IsManagement reports whether the principal is the management canister.
+func (p *Principal) IsManagement() bool {
+	return len(p.Bytes) == 0
+}
+
+// Class returns the class of the principal.
+func (p *Principal) Class() Class {
+	if len(p.Bytes) == 0 {
+		return ClassManagement
+	}
+	switch p.Bytes[len(p.Bytes)-1] {
+	case OPAQUE_SUFFIX:
+		return ClassOpaque
+	case SELF_AUTHENTICATING_SUFFIX:
+		return ClassSelfAuthenticating
+	case DERIVED_SUFFIX:
+		return ClassDerived
+	case ANONYMOUS_SUFFIX:
+		if len(p.Bytes) == 1 {
+			return ClassAnonymous
+		}
+	case RESERVED_SUFFIX:
+		return ClassReserved
+	}
+	return ClassUnassigned
+}
+
 func (p *Principal) ToBytes() []byte {
 	return p.Bytes
 }
@@ -72,12 +166,13 @@ func (p *Principal) ToHex() string {
 	return strings.ToUpper(utils.Hex(p.Bytes))
 }
 
+var groupRegexp = regexp.MustCompile(`.{1,5}`)
+
 func (p *Principal) ToString() string {
 	checkSum := utils.FromUint32(utils.Crc32(p.Bytes))
 	bytes := append(checkSum, p.Bytes...)
 	result := utils.Base32Encode(bytes)
-	re := regexp.MustCompile(`.{1,5}`)
-	matchs := re.FindAllString(result, -1)
+	matchs := groupRegexp.FindAllString(result, -1)
 	return strings.Join(matchs, "-")
 }
 
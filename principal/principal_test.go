@@ -1,6 +1,9 @@
 package principal_test
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/fxamacker/cbor/v2"
@@ -33,3 +36,125 @@ func TestFromHex(t *testing.T) {
 	})
 
 }
+
+func TestFromString(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		for text, class := range map[string]principal.Class{
+			"aaaaa-aa":                    principal.ClassManagement,
+			"2vxsx-fae":                   principal.ClassAnonymous,
+			"rrkah-fqaaa-aaaaa-aaaaq-cai": principal.ClassOpaque,
+			"2chl6-4hpzw-vqaaa-aaaaa-c":   principal.ClassOpaque,
+			"hpikg-6exdt-jn33w-ndty3-fc7jc-tl2lr-buih3-cs3y7-tftkp-sfp62-gqe": principal.ClassSelfAuthenticating,
+			"em77e-bvlzu-aq": principal.ClassOpaque,
+		} {
+			p, err := principal.FromString(text)
+			assert.Nil(t, err, text)
+			assert.Equal(t, text, p.ToString())
+			assert.Equal(t, class, p.Class(), text)
+		}
+		p, err := principal.FromString("RRKAH-FQAAA-AAAAA-AAAAQ-CAI")
+		assert.Nil(t, err)
+		assert.Equal(t, "rrkah-fqaaa-aaaaa-aaaaq-cai", p.ToString())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for text, expected := range map[string]error{
+			"":                             principal.ErrTooShort,
+			"aaa":                          principal.ErrTooShort,
+			"rrkah-fqaaa-aaaaa-aaaaq-ca!":  principal.ErrInvalidCharacter,
+			"rrkah-fqaaa-aaaaa-aaaaq-caa":  principal.ErrInvalidChecksum,
+			"rrkahfqaaa-aaaaa-aaaaq-cai":   principal.ErrInvalidFormat,
+			"rrkah-fqaaa-aaaaa-aaaaq-cai-": principal.ErrInvalidFormat,
+			"hpikg-6exdt-jn33w-ndty3-fc7jc-tl2lr-buih3-cs3y7-tftkp-sfp62-gqeaa-aaaaa": principal.ErrTooLong,
+		} {
+			_, err := principal.FromString(text)
+			assert.True(t, errors.Is(err, expected), "%s: %v", text, err)
+			var parseErr *principal.ParseError
+			assert.True(t, errors.As(err, &parseErr))
+		}
+	})
+
+	t.Run("bytes", func(t *testing.T) {
+		_, err := principal.FromBytes(make([]byte, 30))
+		assert.Equal(t, principal.ErrTooLong, err)
+		p, err := principal.FromBytes(make([]byte, 29))
+		assert.Nil(t, err)
+		assert.Equal(t, principal.ClassUnassigned, p.Class())
+	})
+}
+
+func TestSpecialPrincipals(t *testing.T) {
+	assert.True(t, principal.Anonymous().IsAnonymous())
+	assert.Equal(t, "2vxsx-fae", principal.Anonymous().ToString())
+	assert.True(t, principal.Management().IsManagement())
+	assert.Equal(t, "aaaaa-aa", principal.Management().ToString())
+	assert.Equal(t, "anonymous", principal.Anonymous().Class().String())
+
+	self, err := principal.SelfAuthenticating([]byte("public key"))
+	assert.Nil(t, err)
+	assert.Equal(t, principal.ClassSelfAuthenticating, self.Class())
+	assert.Equal(t, principal.ClassDerived, principal.NewPrincipal([]byte{0x01, 0x03}).Class())
+	assert.Equal(t, principal.ClassReserved, principal.NewPrincipal([]byte{0x7f}).Class())
+	assert.Equal(t, principal.ClassUnassigned, principal.NewPrincipal([]byte{0x01, 0x04}).Class())
+}
+
+func TestEncoding(t *testing.T) {
+	p, _ := principal.FromString("rrkah-fqaaa-aaaaa-aaaaq-cai")
+	type config struct {
+		Canister principal.Principal  `json:"canister" cbor:"canister"`
+		Owner    *principal.Principal `json:"owner" cbor:"owner"`
+	}
+
+	t.Run("json", func(t *testing.T) {
+		data, err := json.Marshal(config{Canister: *p, Owner: principal.Anonymous()})
+		assert.Nil(t, err)
+		assert.Equal(t, `{"canister":"rrkah-fqaaa-aaaaa-aaaaq-cai","owner":"2vxsx-fae"}`, string(data))
+		var decoded config
+		assert.Nil(t, json.Unmarshal(data, &decoded))
+		assert.True(t, p.Equal(&decoded.Canister))
+		assert.True(t, decoded.Owner.IsAnonymous())
+		assert.NotNil(t, json.Unmarshal([]byte(`{"canister":"rrkah"}`), &decoded))
+	})
+
+	t.Run("text", func(t *testing.T) {
+		text, err := p.MarshalText()
+		assert.Nil(t, err)
+		var decoded principal.Principal
+		assert.Nil(t, decoded.UnmarshalText(text))
+		assert.True(t, p.Equal(&decoded))
+		assert.NotNil(t, decoded.UnmarshalText([]byte("rrkah-fqaaa")))
+		assert.Equal(t, "rrkah-fqaaa-aaaaa-aaaaq-cai", p.String())
+		assert.Equal(t, "rrkah-fqaaa-aaaaa-aaaaq-cai", fmt.Sprint(*p))
+	})
+
+	t.Run("cbor", func(t *testing.T) {
+		data, err := cbor.Marshal(p)
+		assert.Nil(t, err)
+		assert.Equal(t, []byte{0x4a, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x1, 0x1, 0x1}, data)
+		data, err = cbor.Marshal(config{Canister: *p, Owner: p})
+		assert.Nil(t, err)
+		var decoded config
+		assert.Nil(t, cbor.Unmarshal(data, &decoded))
+		assert.Equal(t, *p, decoded.Canister)
+		assert.Equal(t, p, decoded.Owner)
+	})
+
+	t.Run("sql", func(t *testing.T) {
+		value, err := p.Value()
+		assert.Nil(t, err)
+		assert.Equal(t, "rrkah-fqaaa-aaaaa-aaaaq-cai", value)
+		var scanned principal.Principal
+		assert.Nil(t, scanned.Scan(value))
+		assert.True(t, p.Equal(&scanned))
+		assert.Nil(t, scanned.Scan([]byte("2vxsx-fae")))
+		assert.True(t, scanned.IsAnonymous())
+		assert.NotNil(t, scanned.Scan(42))
+	})
+
+	t.Run("compare", func(t *testing.T) {
+		assert.Equal(t, 0, p.Compare(p))
+		assert.Equal(t, -1, principal.Management().Compare(p))
+		assert.Equal(t, 1, principal.Anonymous().Compare(p))
+		assert.False(t, p.Equal(principal.Anonymous()))
+	})
+}